@@ -1,38 +1,37 @@
 package main
 
 import (
-	"net/http"
+	"fmt"
 	"os"
-
-	"github.com/vickiliou/challenge-wex/config"
-	"github.com/vickiliou/challenge-wex/database"
-	"golang.org/x/exp/slog"
+	"strings"
 )
 
-func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slog.SetDefault(logger)
-
-	db, err := database.Setup()
-	if err != nil {
-		slog.Warn("Failed to open SQLite database", "error", err.Error())
-		return
-	}
-	defer db.Close()
-
-	r := config.SetupRouter(db)
-
-	errCh := make(chan error, 1)
+// usage describes the commands of the binary.
+const usage = `Usage:
+  main [serve] [flags]            run the HTTP server
+  main migrate <command> [flags]  run a database migration command:
+                                    up       apply the pending migrations
+                                    down     roll back the last migration
+                                    status   list the migrations and whether they are applied
+                                    version  print the current version
+                                    redo     roll back the last migration and apply it again
 
-	go func() {
-		slog.Info("Starting server")
-		if err := http.ListenAndServe(":8082", r); err != nil {
-			errCh <- err
-		}
-	}()
+Both commands take the configuration flags, see --help.
+`
 
-	if err := <-errCh; err != nil {
-		slog.Error("Server error", slog.String("error", err.Error()))
+func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
+	switch command {
+	case "serve":
+		os.Exit(serve(args))
+	case "migrate":
+		os.Exit(migrate(args))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
 }
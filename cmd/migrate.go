@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vickiliou/challenge-wex/config"
+	"github.com/vickiliou/challenge-wex/database"
+	"golang.org/x/exp/slog"
+)
+
+// migrate runs a migration command against the configured database and returns the exit code of the process,
+// so that a deployment can gate on it.
+func migrate(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "missing migration command\n\n%s", usage)
+		return 2
+	}
+	command := args[0]
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		slog.Error("Failed to load configuration", "error", err.Error())
+		return 2
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.Log.SlogLevel()}))
+	slog.SetDefault(logger)
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		return 1
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db, cfg.Database.Driver, command, os.Stdout); err != nil {
+		if errors.Is(err, database.ErrUnknownMigrationCommand) {
+			fmt.Fprintf(os.Stderr, "%s\n\n%s", err.Error(), usage)
+			return 2
+		}
+		slog.Error("Failed to run migration", "command", command, "error", err.Error())
+		return 1
+	}
+
+	return 0
+}
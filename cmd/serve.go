@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/pflag"
+	"github.com/vickiliou/challenge-wex/config"
+	"github.com/vickiliou/challenge-wex/database"
+	"github.com/vickiliou/challenge-wex/internal/metrics"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
+	"golang.org/x/exp/slog"
+)
+
+// serve runs the HTTP server until it receives SIGTERM or SIGINT and returns the exit code of the process.
+func serve(args []string) int {
+	cfg, err := config.Load(args)
+	if err != nil {
+		if !errors.Is(err, pflag.ErrHelp) {
+			slog.Error("Failed to load configuration", "error", err.Error())
+			return 2
+		}
+		return 0
+	}
+
+	logger := slog.New(tracing.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.Log.SlogLevel()})))
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Options(), os.Stdout)
+	if err != nil {
+		slog.Warn("Failed to set up tracing", "exporter", cfg.Tracing.Exporter, "error", err.Error())
+		return 1
+	}
+	defer func() {
+		// Flushes the spans of the last requests, so it runs once everything else has stopped.
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	db, err := database.Setup(cfg.Database.Driver, cfg.Database.DSN, cfg.Database.AutoMigrate)
+	if err != nil {
+		slog.Warn("Failed to open database", "driver", cfg.Database.Driver, "error", err.Error())
+		return 1
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("Failed to close database", slog.String("error", err.Error()))
+			return
+		}
+		slog.Info("Database closed")
+	}()
+
+	m := metrics.New()
+	m.RegisterDB(db, cfg.Database.Driver)
+
+	// The router and the rate sync share the gateway, and so the circuit breaker of the Treasury API.
+	gw := config.SetupGateway(cfg, m)
+
+	r, err := config.SetupRouter(cfg, db, m, gw)
+	if err != nil {
+		slog.Warn("Failed to configure exchange rate providers", "error", err.Error())
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// workersCtx is cancelled only once the server has drained, so background work outlives in-flight requests.
+	workersCtx, cancelWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		cancelWorkers()
+		workers.Wait()
+		slog.Info("Background workers stopped")
+	}()
+
+	if cfg.RateSync.Interval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			config.SetupRateSync(cfg, db, m, gw).Run(workersCtx, cfg.RateSync.Interval)
+		}()
+	}
+
+	srv := config.SetupServer(cfg, r)
+	errCh := make(chan error, 1)
+
+	go func() {
+		slog.Info("Starting server", "addr", cfg.Server.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		slog.Error("Server error", slog.String("error", err.Error()))
+		return 1
+	case <-ctx.Done():
+		stop()
+		slog.Info("Shutting down server", slog.Duration("timeout", cfg.Server.ShutdownTimeout))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain in-flight requests", slog.String("error", err.Error()))
+		return 1
+	}
+
+	slog.Info("Server stopped")
+	return 0
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+// errUsage is returned by a command invoked with missing or unexpected arguments.
+var errUsage = errors.New("invalid usage")
+
+type service interface {
+	Create(ctx context.Context, input transaction.RecordRequest) (string, error)
+	Import(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error)
+	Get(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error)
+	List(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error)
+	Export(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error
+}
+
+type rateSyncer interface {
+	Sync(ctx context.Context) (int, error)
+}
+
+// cli represents what a command runs against: the service, the exchange rate syncer, the printer of its results
+// and the positional arguments it was given.
+type cli struct {
+	svc   service
+	rates rateSyncer
+	out   *printer
+	args  []string
+}
+
+// command runs a command whose flags have been parsed.
+type command func(ctx context.Context, c *cli) error
+
+// commands maps the name of every command to the function adding its flags to a flag set and returning it.
+var commands = map[string]func(fs *pflag.FlagSet) command{
+	"create": createCommand,
+	"import": importCommand,
+	"get":    getCommand,
+	"list":   listCommand,
+	"export": exportCommand,
+	"sync":   syncCommand,
+}
+
+// syncResult represents the outcome of a rate sync.
+type syncResult struct {
+	Synced int `json:"synced"`
+}
+
+// createCommand creates a transaction and prints its ID.
+func createCommand(fs *pflag.FlagSet) command {
+	description := fs.String("description", "", "description of the transaction")
+	date := fs.String("date", "", "date of the transaction, as YYYY-MM-DD or RFC 3339")
+	amount := fs.String("amount", "", "amount of the transaction in USD, e.g. 10.50")
+
+	return func(ctx context.Context, c *cli) error {
+		if err := noArgs(c.args); err != nil {
+			return err
+		}
+
+		input := transaction.RecordRequest{Description: *description}
+
+		var err error
+		if input.TransactionDate, err = parseTime(*date); err != nil {
+			return err
+		}
+		if input.Amount, err = money.Parse(*amount, money.USD); err != nil {
+			return fmt.Errorf("invalid amount: %w", err)
+		}
+
+		id, err := c.svc.Create(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		return c.out.record(transaction.RecordResponse{ID: id})
+	}
+}
+
+// importCommand creates the transactions of a file and prints the outcome of every row.
+func importCommand(fs *pflag.FlagSet) command {
+	file := fs.String("file", "", "path of the file to import, - for the standard input")
+	format := fs.String("format", transaction.ImportCSV, "format of the file: csv or jsonl")
+
+	return func(ctx context.Context, c *cli) error {
+		if err := noArgs(c.args); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("%w: --file is required", errUsage)
+		}
+
+		body := os.Stdin
+		if *file != "-" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			body = f
+		}
+
+		res, err := c.svc.Import(ctx, transaction.ImportRequest{Format: *format, Body: body})
+		if err != nil {
+			return err
+		}
+
+		return c.out.importResponse(res)
+	}
+}
+
+// getCommand prints a transaction converted to a currency.
+func getCommand(fs *pflag.FlagSet) command {
+	country := fs.String("country", "", "country of the target currency, as an ISO 3166 code or a Treasury country name")
+	currency := fs.String("currency", "", "target currency, as an ISO 4217 code or a Treasury currency name")
+
+	return func(ctx context.Context, c *cli) error {
+		if len(c.args) != 1 {
+			return fmt.Errorf("%w: get takes the ID of a transaction", errUsage)
+		}
+
+		res, err := c.svc.Get(ctx, transaction.RetrieveRequest{
+			ID:       c.args[0],
+			Country:  *country,
+			Currency: *currency,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.out.retrieveResponse(res)
+	}
+}
+
+// listCommand prints a page of transactions in their original currency.
+func listCommand(fs *pflag.FlagSet) command {
+	var input transaction.ListRequest
+	addFilterFlags(fs, &input.From, &input.To, &input.MinAmount, &input.MaxAmount, &input.Description)
+	fs.StringVar(&input.Sort, "sort", "", "sort order: transaction_date or amount, prefixed with - for descending, -transaction_date by default")
+	fs.StringVar(&input.Limit, "limit", "", "largest number of transactions listed")
+	fs.StringVar(&input.Cursor, "cursor", "", "cursor of the page to list, from a previous list")
+
+	return func(ctx context.Context, c *cli) error {
+		if err := noArgs(c.args); err != nil {
+			return err
+		}
+
+		res, err := c.svc.List(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		return c.out.listResponse(res)
+	}
+}
+
+// exportCommand prints every transaction matching the filters converted to a currency, as they are read.
+func exportCommand(fs *pflag.FlagSet) command {
+	var input transaction.ExportRequest
+	f := &input.Filter
+	addFilterFlags(fs, &f.From, &f.To, &f.MinAmount, &f.MaxAmount, &f.Description)
+	fs.StringVar(&input.Country, "country", "", "country of the target currency, as an ISO 3166 code or a Treasury country name")
+	fs.StringVar(&input.Currency, "currency", "", "target currency, as an ISO 4217 code or a Treasury currency name")
+
+	return func(ctx context.Context, c *cli) error {
+		if err := noArgs(c.args); err != nil {
+			return err
+		}
+
+		ew := c.out.exportWriter()
+		if err := c.svc.Export(ctx, input, ew.write); err != nil {
+			return err
+		}
+
+		return ew.close()
+	}
+}
+
+// syncCommand syncs the local exchange rate store with the Treasury dataset and prints the number of records synced.
+func syncCommand(fs *pflag.FlagSet) command {
+	return func(ctx context.Context, c *cli) error {
+		if err := noArgs(c.args); err != nil {
+			return err
+		}
+
+		synced, err := c.rates.Sync(ctx)
+		if err != nil {
+			return err
+		}
+
+		return c.out.syncResult(syncResult{Synced: synced})
+	}
+}
+
+// addFilterFlags adds the flags filtering the transactions of a list or an export.
+func addFilterFlags(fs *pflag.FlagSet, from, to, minAmount, maxAmount, description *string) {
+	fs.StringVar(from, "from", "", "first transaction date, as YYYY-MM-DD")
+	fs.StringVar(to, "to", "", "last transaction date, as YYYY-MM-DD")
+	fs.StringVar(minAmount, "min-amount", "", "smallest amount in USD")
+	fs.StringVar(maxAmount, "max-amount", "", "largest amount in USD")
+	fs.StringVar(description, "description", "", "text the description contains")
+}
+
+// noArgs checks that a command was not given positional arguments.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, args[0])
+	}
+
+	return nil
+}
+
+// parseTime parses a transaction date given as a day, which is taken in UTC, or as an RFC 3339 timestamp.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", s)
+	}
+
+	return t, nil
+}
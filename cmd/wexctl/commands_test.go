@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+type stubService struct {
+	create    func(ctx context.Context, input transaction.RecordRequest) (string, error)
+	importTxn func(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error)
+	get       func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error)
+	list      func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error)
+	export    func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error
+}
+
+func (s *stubService) Create(ctx context.Context, input transaction.RecordRequest) (string, error) {
+	return s.create(ctx, input)
+}
+
+func (s *stubService) Import(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error) {
+	return s.importTxn(ctx, input)
+}
+
+func (s *stubService) Get(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error) {
+	return s.get(ctx, input)
+}
+
+func (s *stubService) List(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+	return s.list(ctx, input)
+}
+
+func (s *stubService) Export(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+	return s.export(ctx, input, write)
+}
+
+type stubRateSyncer struct {
+	sync func(ctx context.Context) (int, error)
+}
+
+func (s *stubRateSyncer) Sync(ctx context.Context) (int, error) {
+	return s.sync(ctx)
+}
+
+// runCommand parses the arguments of a command and runs it, returning what it printed.
+func runCommand(t *testing.T, name string, args []string, svc service, rates rateSyncer) (string, error) {
+	t.Helper()
+
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	output := fs.StringP("output", "o", outputTable, "")
+	cmd := commands[name](fs)
+	require.NoError(t, fs.Parse(args))
+
+	var out bytes.Buffer
+	p, err := newPrinter(&out, *output)
+	require.NoError(t, err)
+
+	err = cmd(context.Background(), &cli{svc: svc, rates: rates, out: p, args: fs.Args()})
+	return out.String(), err
+}
+
+var txnDate = time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+
+func TestCreate(t *testing.T) {
+	testCases := map[string]struct {
+		args    []string
+		want    string
+		wantReq transaction.RecordRequest
+	}{
+		"table": {
+			args:    []string{"--description", "coffee", "--date", "2023-09-21", "--amount", "10.50"},
+			want:    "ID\nf47ac10b-58cc-4372-a567-0e02b2c3d479\n",
+			wantReq: transaction.RecordRequest{Description: "coffee", TransactionDate: txnDate, Amount: money.MustParse("10.50", money.USD)},
+		},
+		"json with an RFC 3339 date": {
+			args:    []string{"--description", "coffee", "--date", "2023-09-21T10:00:00Z", "--amount", "1", "-o", "json"},
+			want:    "{\n  \"id\": \"f47ac10b-58cc-4372-a567-0e02b2c3d479\"\n}\n",
+			wantReq: transaction.RecordRequest{Description: "coffee", TransactionDate: txnDate.Add(10 * time.Hour), Amount: money.MustParse("1", money.USD)},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var gotReq transaction.RecordRequest
+			svc := &stubService{
+				create: func(ctx context.Context, input transaction.RecordRequest) (string, error) {
+					gotReq = input
+					return "f47ac10b-58cc-4372-a567-0e02b2c3d479", nil
+				},
+			}
+
+			got, err := runCommand(t, "create", tc.args, svc, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantReq, gotReq)
+		})
+	}
+}
+
+func TestCreate_Error(t *testing.T) {
+	testCases := map[string]struct {
+		args    []string
+		wantErr string
+	}{
+		"invalid date": {
+			args:    []string{"--description", "coffee", "--date", "21/09/2023", "--amount", "1"},
+			wantErr: `invalid date "21/09/2023", expected YYYY-MM-DD or RFC 3339`,
+		},
+		"invalid amount": {
+			args:    []string{"--description", "coffee", "--date", "2023-09-21", "--amount", "1.001"},
+			wantErr: "invalid amount: amount must be rounded to two decimal places",
+		},
+		"unexpected argument": {
+			args:    []string{"coffee"},
+			wantErr: `invalid usage: unexpected argument "coffee"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := runCommand(t, "create", tc.args, &stubService{}, nil)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	var gotReq transaction.RetrieveRequest
+	svc := &stubService{
+		get: func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error) {
+			gotReq = input
+			return &transaction.RetrieveResponse{
+				ID:              input.ID,
+				Description:     "coffee\tbeans",
+				TransactionDate: txnDate,
+				OriginalAmount:  money.MustParse("10.50", money.USD),
+				ExchangeRate:    money.MustParseRate("1.35"),
+				ConvertedAmount: money.MustParse("14.18", "CAD"),
+				RateDate:        "2023-06-30",
+				RateProvider:    "treasury",
+			}, nil
+		},
+	}
+
+	got, err := runCommand(t, "get", []string{"f47ac10b-58cc-4372-a567-0e02b2c3d479", "--country", "Canada", "--currency", "Dollar"}, svc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, transaction.RetrieveRequest{ID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", Country: "Canada", Currency: "Dollar"}, gotReq)
+	assert.Equal(t, ""+
+		"ID                                    DESCRIPTION   TRANSACTION_DATE      ORIGINAL_AMOUNT  EXCHANGE_RATE  EXCHANGE_RATE_DATE  CONVERTED_AMOUNT  CURRENCY  PROVIDER\n"+
+		"f47ac10b-58cc-4372-a567-0e02b2c3d479  coffee beans  2023-09-21T00:00:00Z  10.50            1.35           2023-06-30          14.18             CAD       treasury\n",
+		got)
+
+	_, err = runCommand(t, "get", nil, svc, nil)
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func TestList(t *testing.T) {
+	var gotReq transaction.ListRequest
+	svc := &stubService{
+		list: func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+			gotReq = input
+			return &transaction.ListResponse{
+				Data: []transaction.TransactionResponse{
+					{ID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", Description: "coffee", TransactionDate: txnDate, Amount: money.MustParse("10.50", money.USD)},
+				},
+				NextCursor: "abc",
+			}, nil
+		},
+	}
+
+	got, err := runCommand(t, "list", []string{"--from", "2023-09-01", "--min-amount", "5", "--sort", "amount", "--limit", "1"}, svc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, transaction.ListRequest{From: "2023-09-01", MinAmount: "5", Sort: "amount", Limit: "1"}, gotReq)
+	assert.Equal(t, ""+
+		"ID                                    DESCRIPTION  TRANSACTION_DATE      AMOUNT\n"+
+		"f47ac10b-58cc-4372-a567-0e02b2c3d479  coffee       2023-09-21T00:00:00Z  10.50\n"+
+		"\nNext page: --cursor abc\n",
+		got)
+}
+
+func TestExport(t *testing.T) {
+	rows := []transaction.ExportRow{
+		{ID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", Description: "coffee", TransactionDate: txnDate, OriginalAmount: money.MustParse("10.50", money.USD), Error: "no exchange rate"},
+	}
+
+	testCases := map[string]struct {
+		args []string
+		rows []transaction.ExportRow
+		want string
+	}{
+		"table": {
+			args: []string{"--currency", "CAD", "--from", "2023-09-01"},
+			rows: rows,
+			want: "" +
+				"id                                    description  transaction_date      original_amount  exchange_rate  exchange_rate_date  converted_amount  country_currency_desc  exchange_rate_provider  error\n" +
+				"f47ac10b-58cc-4372-a567-0e02b2c3d479  coffee       2023-09-21T00:00:00Z  10.50                                                                                                                no exchange rate\n",
+		},
+		"json": {
+			args: []string{"--currency", "CAD", "--from", "2023-09-01", "-o", "json"},
+			rows: rows,
+			want: `{"id":"f47ac10b-58cc-4372-a567-0e02b2c3d479","description":"coffee","transaction_date":"2023-09-21T00:00:00Z","original_amount":10.50,"error":"no exchange rate"}` + "\n",
+		},
+		"no rows": {
+			args: []string{"--currency", "CAD", "--from", "2023-09-01"},
+			want: "id  description  transaction_date  original_amount  exchange_rate  exchange_rate_date  converted_amount  country_currency_desc  exchange_rate_provider  error\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var gotReq transaction.ExportRequest
+			svc := &stubService{
+				export: func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+					gotReq = input
+					for _, row := range tc.rows {
+						if err := write(row); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}
+
+			got, err := runCommand(t, "export", tc.args, svc, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, transaction.ExportRequest{Filter: transaction.ConversionFilter{From: "2023-09-01"}, Currency: "CAD"}, gotReq)
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	rates := &stubRateSyncer{
+		sync: func(ctx context.Context) (int, error) {
+			return 42, nil
+		},
+	}
+
+	got, err := runCommand(t, "sync", []string{"-o", "json"}, nil, rates)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"synced\": 42\n}\n", got)
+
+	errGateway := errors.New("gateway unavailable")
+	rates.sync = func(ctx context.Context) (int, error) {
+		return 0, errGateway
+	}
+
+	_, err = runCommand(t, "sync", nil, nil, rates)
+	assert.ErrorIs(t, err, errGateway)
+}
+
+func TestRun_Usage(t *testing.T) {
+	testCases := map[string]struct {
+		args []string
+		want int
+	}{
+		"no command":       {args: nil, want: 2},
+		"unknown command":  {args: []string{"delete"}, want: 2},
+		"unknown flag":     {args: []string{"list", "--unknown"}, want: 2},
+		"invalid output":   {args: []string{"list", "-o", "yaml"}, want: 2},
+		"help of commands": {args: []string{"get", "--help"}, want: 0},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, run(tc.args, io.Discard, io.Discard))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Command wexctl manages transactions through the service layer, directly against the database,
+// for when the HTTP server is unavailable and for scripted backfills.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/pflag"
+	"github.com/vickiliou/challenge-wex/config"
+	"github.com/vickiliou/challenge-wex/database"
+	"github.com/vickiliou/challenge-wex/internal/metrics"
+	"golang.org/x/exp/slog"
+)
+
+// usage describes the commands of wexctl.
+const usage = `Usage:
+  wexctl create --description <text> --date <date> --amount <amount> [flags]  create a transaction
+  wexctl import --file <path> [--format csv|jsonl] [flags]                    create the transactions of a file
+  wexctl get <id> --currency <currency> [--country <country>] [flags]         get a transaction converted to a currency
+  wexctl list [--from <date>] [--to <date>] [--sort <sort>] [flags]           list a page of transactions
+  wexctl export --currency <currency> [--from <date>] [--to <date>] [flags]   export converted transactions
+  wexctl sync [flags]                                                         sync the exchange rates from the Treasury
+
+Every command takes --output table|json and the configuration flags of the server, see wexctl <command> --help.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command of the arguments and returns the exit code of the process: 0 on success,
+// 1 if the command failed and 2 if it was not invoked correctly.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	name := args[0]
+
+	newCommand, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+
+	fs := pflag.NewFlagSet("wexctl "+name, pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.StringP("output", "o", outputTable, "output format: table or json")
+	cmd := newCommand(fs)
+
+	cfg, err := config.LoadFlagSet(fs, args[1:])
+	if err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		return 2
+	}
+
+	p, err := newPrinter(stdout, *output)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		return 2
+	}
+
+	logger := slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: cfg.Log.SlogLevel()}))
+	slog.SetDefault(logger)
+
+	db, err := database.Setup(cfg.Database.Driver, cfg.Database.DSN, cfg.Database.AutoMigrate)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	// The metrics are not served, but the configuration functions record in them.
+	m := metrics.New()
+
+	gw := config.SetupGateway(cfg, m)
+
+	svc, err := config.SetupService(cfg, db, m, gw)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &cli{
+		svc:   svc,
+		rates: config.SetupRateSync(cfg, db, m, gw),
+		out:   p,
+		args:  fs.Args(),
+	}
+
+	if err := cmd(ctx, c); err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// exportFlushRows is the number of rows after which an export printed as a table is flushed, so that its
+// columns are aligned in blocks of rows rather than over the whole export, which would have to be held in memory.
+const exportFlushRows = 500
+
+// printer prints the results of the commands as aligned tables, or as indented JSON documents.
+// Exports are printed as JSONL, with one row per line.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// newPrinter creates a printer of the given output format.
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case outputTable, outputJSON:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("output must be %s or %s", outputTable, outputJSON)
+	}
+}
+
+// record prints the ID of a created transaction.
+func (p *printer) record(res transaction.RecordResponse) error {
+	if p.format == outputJSON {
+		return p.json(res)
+	}
+
+	return p.table([]string{"ID"}, [][]string{{res.ID}})
+}
+
+// importResponse prints the outcome of every row of an import, followed by the number of accepted and rejected rows.
+func (p *printer) importResponse(res *transaction.ImportResponse) error {
+	if p.format == outputJSON {
+		return p.json(res)
+	}
+
+	rows := make([][]string, 0, len(res.Rows))
+	for _, r := range res.Rows {
+		rows = append(rows, []string{strconv.Itoa(r.Line), r.ID, r.Error})
+	}
+
+	if err := p.table([]string{"LINE", "ID", "ERROR"}, rows); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(p.w, "\n%d accepted, %d rejected\n", res.Accepted, res.Rejected)
+	return err
+}
+
+// retrieveResponse prints a converted transaction.
+func (p *printer) retrieveResponse(res *transaction.RetrieveResponse) error {
+	if p.format == outputJSON {
+		return p.json(res)
+	}
+
+	return p.table(
+		[]string{"ID", "DESCRIPTION", "TRANSACTION_DATE", "ORIGINAL_AMOUNT", "EXCHANGE_RATE", "EXCHANGE_RATE_DATE", "CONVERTED_AMOUNT", "CURRENCY", "PROVIDER"},
+		[][]string{{
+			res.ID,
+			res.Description,
+			res.TransactionDate.Format(time.RFC3339),
+			res.OriginalAmount.String(),
+			res.ExchangeRate.String(),
+			res.RateDate,
+			res.ConvertedAmount.String(),
+			res.ConvertedAmount.Currency(),
+			res.RateProvider,
+		}},
+	)
+}
+
+// listResponse prints a page of transactions, followed by the cursor of the next page if there is one.
+func (p *printer) listResponse(res *transaction.ListResponse) error {
+	if p.format == outputJSON {
+		return p.json(res)
+	}
+
+	rows := make([][]string, 0, len(res.Data))
+	for _, txn := range res.Data {
+		rows = append(rows, []string{txn.ID, txn.Description, txn.TransactionDate.Format(time.RFC3339), txn.Amount.String()})
+	}
+
+	if err := p.table([]string{"ID", "DESCRIPTION", "TRANSACTION_DATE", "AMOUNT"}, rows); err != nil {
+		return err
+	}
+
+	if res.NextCursor == "" {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(p.w, "\nNext page: --cursor %s\n", res.NextCursor)
+	return err
+}
+
+// syncResult prints the number of exchange rate records synced.
+func (p *printer) syncResult(res syncResult) error {
+	if p.format == outputJSON {
+		return p.json(res)
+	}
+
+	return p.table([]string{"SYNCED"}, [][]string{{strconv.Itoa(res.Synced)}})
+}
+
+// json prints a value as an indented JSON document.
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// table prints a header and rows with their columns aligned.
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := newTabWriter(p.w)
+
+	writeRow(tw, header)
+	for _, row := range rows {
+		writeRow(tw, row)
+	}
+
+	return tw.Flush()
+}
+
+// exportWriter returns the writer of the rows of an export.
+func (p *printer) exportWriter() *exportWriter {
+	ew := &exportWriter{format: p.format}
+
+	if p.format == outputJSON {
+		ew.json = json.NewEncoder(p.w)
+	} else {
+		ew.table = newTabWriter(p.w)
+	}
+
+	return ew
+}
+
+// exportWriter prints the rows of an export as they are read, as JSONL or as a table.
+type exportWriter struct {
+	format string
+	json   *json.Encoder
+	table  *tabwriter.Writer
+	rows   int
+}
+
+// write prints a row, preceded by the header for the first row of a table.
+func (e *exportWriter) write(row transaction.ExportRow) error {
+	if e.format == outputJSON {
+		return e.json.Encode(row)
+	}
+
+	if e.rows == 0 {
+		writeRow(e.table, transaction.ExportHeader)
+	}
+
+	writeRow(e.table, row.Record())
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.table.Flush()
+	}
+
+	return nil
+}
+
+// close completes the export, printing the header of a table even if no row matched.
+func (e *exportWriter) close() error {
+	if e.format == outputJSON {
+		return nil
+	}
+
+	if e.rows == 0 {
+		writeRow(e.table, transaction.ExportHeader)
+	}
+
+	return e.table.Flush()
+}
+
+// newTabWriter creates a writer aligning tab separated columns.
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// cellReplacer replaces the tabs and line breaks within cells by spaces so that they cannot break the alignment.
+var cellReplacer = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// writeRow writes the cells of a row separated by tabs.
+func writeRow(tw *tabwriter.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			fmt.Fprint(tw, "\t")
+		}
+		fmt.Fprint(tw, cellReplacer.Replace(cell))
+	}
+	fmt.Fprintln(tw)
+}
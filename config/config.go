@@ -0,0 +1,413 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/vickiliou/challenge-wex/database"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/repository"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
+	"golang.org/x/exp/slog"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration,
+// e.g. WEX_SERVER_LISTEN_ADDR for server.listen_addr.
+const envPrefix = "WEX"
+
+// Config represents the application configuration.
+type Config struct {
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Treasury     TreasuryConfig     `mapstructure:"treasury"`
+	HTTPClient   HTTPClientConfig   `mapstructure:"http_client"`
+	ExchangeRate ExchangeRateConfig `mapstructure:"exchange_rate"`
+	RateSync     RateSyncConfig     `mapstructure:"rate_sync"`
+	Log          LogConfig          `mapstructure:"log"`
+	Tracing      TracingConfig      `mapstructure:"tracing"`
+}
+
+// ServerConfig represents the configuration of the HTTP server.
+type ServerConfig struct {
+	ListenAddr        string        `mapstructure:"listen_addr"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+
+	// ShutdownTimeout is how long in-flight requests are given to complete once a shutdown signal is received.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// DatabaseConfig represents the configuration of the database.
+type DatabaseConfig struct {
+	// Driver is database.DriverSQLite or database.DriverPostgres.
+	Driver string `mapstructure:"driver"`
+	DSN    string `mapstructure:"dsn"`
+
+	// AutoMigrate applies the pending migrations when the server starts. When disabled, the migrations must
+	// be run beforehand with the migrate command, and the server refuses to start on an outdated database.
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+}
+
+// Dialect returns the SQL dialect of the repositories. It must only be called on a validated configuration.
+func (c DatabaseConfig) Dialect() repository.Dialect {
+	return repository.Dialect(c.Driver)
+}
+
+// TreasuryConfig represents the configuration of the Treasury Reporting Rates of Exchange API.
+type TreasuryConfig struct {
+	BaseURL string `mapstructure:"base_url"`
+
+	// CallTimeout is the budget of each call to the API. A call made while serving a request
+	// is also cancelled when the request is.
+	CallTimeout time.Duration `mapstructure:"call_timeout"`
+
+	Retry   RetryConfig   `mapstructure:"retry"`
+	Breaker BreakerConfig `mapstructure:"breaker"`
+}
+
+// RetryConfig represents how failed calls to an external API are retried.
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BaseDelay   time.Duration `mapstructure:"base_delay"`
+	MaxDelay    time.Duration `mapstructure:"max_delay"`
+}
+
+// BreakerConfig represents the circuit breaker protecting an external API.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed calls opening the circuit.
+	FailureThreshold int `mapstructure:"failure_threshold"`
+
+	// OpenTimeout is how long calls fail fast once the circuit is open, before a probe call is let through.
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+}
+
+// HTTPClientConfig represents the configuration of the client calling external APIs.
+type HTTPClientConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ExchangeRateConfig represents the configuration of the exchange rate providers.
+type ExchangeRateConfig struct {
+	// Providers is the order in which providers are tried. When empty, every configured provider is tried.
+	Providers []string `mapstructure:"providers"`
+
+	// File is the path of a CSV or JSON file of exchange rates.
+	File string `mapstructure:"file"`
+
+	// Overrides are fixed exchange rates by case-insensitive country currency description, e.g. {"Canada-Dollar": "1.35"}.
+	Overrides map[string]string `mapstructure:"overrides"`
+
+	// LookbackMonths is how many months before the transaction date an exchange rate may be used.
+	LookbackMonths int `mapstructure:"lookback_months"`
+
+	// Policy is the rate selection policy used when a request does not choose one: on_or_before, nearest or quarter_start.
+	Policy string `mapstructure:"policy"`
+
+	Cache CacheConfig `mapstructure:"cache"`
+}
+
+// CacheConfig represents the in-memory cache of the Treasury exchange rates.
+type CacheConfig struct {
+	// Size is the maximum number of cached rates. Zero disables the cache.
+	Size int `mapstructure:"size"`
+
+	// TTL is how long a rate is cached.
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// NegativeTTL is how long the absence of a rate is cached. Zero disables negative caching.
+	NegativeTTL time.Duration `mapstructure:"negative_ttl"`
+}
+
+// RatePolicy returns the default rate selection policy. It must only be called on a validated configuration.
+func (c ExchangeRateConfig) RatePolicy() gateway.RatePolicy {
+	policy, _ := gateway.ParsePolicy(c.Policy)
+	return policy
+}
+
+// RateSyncConfig represents the configuration of the exchange rate synchronization.
+type RateSyncConfig struct {
+	// Interval is how often the local exchange rates are synced with the Treasury dataset. Zero disables the sync.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// LogConfig represents the configuration of the logger.
+type LogConfig struct {
+	Level string `mapstructure:"level"`
+}
+
+// SlogLevel returns the log level. It must only be called on a validated configuration.
+func (c LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Level))
+	return level
+}
+
+// TracingConfig represents the configuration of the OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is where spans are exported: none, stdout or otlp.
+	Exporter string `mapstructure:"exporter"`
+
+	// Endpoint is the URL of the OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces. When empty,
+	// the OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables are used.
+	Endpoint string `mapstructure:"endpoint"`
+
+	ServiceName string `mapstructure:"service_name"`
+
+	// SampleRatio is the fraction of the traces started by the service that are sampled, between 0 and 1.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// Options returns the options of the tracing setup.
+func (c TracingConfig) Options() tracing.Options {
+	return tracing.Options{
+		Exporter:    c.Exporter,
+		Endpoint:    c.Endpoint,
+		ServiceName: c.ServiceName,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// defaults holds the default value of every configuration key.
+var defaults = map[string]any{
+	"server.listen_addr":                 ":8082",
+	"server.read_header_timeout":         5 * time.Second,
+	"server.read_timeout":                15 * time.Second,
+	"server.write_timeout":               60 * time.Second,
+	"server.idle_timeout":                120 * time.Second,
+	"server.shutdown_timeout":            30 * time.Second,
+	"database.driver":                    database.DriverSQLite,
+	"database.dsn":                       "wex.db",
+	"database.auto_migrate":              true,
+	"treasury.base_url":                  gateway.DefaultBaseURL,
+	"treasury.call_timeout":              5 * time.Second,
+	"treasury.retry.max_attempts":        3,
+	"treasury.retry.base_delay":          200 * time.Millisecond,
+	"treasury.retry.max_delay":           2 * time.Second,
+	"treasury.breaker.failure_threshold": 5,
+	"treasury.breaker.open_timeout":      30 * time.Second,
+	"http_client.timeout":                10 * time.Second,
+	"exchange_rate.providers":            []string{},
+	"exchange_rate.file":                 "",
+	"exchange_rate.overrides":            map[string]string{},
+	"exchange_rate.lookback_months":      gateway.DefaultLookbackMonths,
+	"exchange_rate.policy":               string(gateway.DefaultPolicy),
+	"exchange_rate.cache.size":           10000,
+	"exchange_rate.cache.ttl":            time.Hour,
+	"exchange_rate.cache.negative_ttl":   5 * time.Minute,
+	"rate_sync.interval":                 24 * time.Hour,
+	"log.level":                          "info",
+	"tracing.exporter":                   tracing.ExporterNone,
+	"tracing.endpoint":                   "",
+	"tracing.service_name":               "wex",
+	"tracing.sample_ratio":               1.0,
+}
+
+// flags maps the command line flags to their configuration key.
+var flags = map[string]string{
+	"listen-addr":      "server.listen_addr",
+	"db-driver":        "database.driver",
+	"db-dsn":           "database.dsn",
+	"auto-migrate":     "database.auto_migrate",
+	"treasury-url":     "treasury.base_url",
+	"treasury-timeout": "treasury.call_timeout",
+	"http-timeout":     "http_client.timeout",
+	"lookback-months":  "exchange_rate.lookback_months",
+	"rate-policy":      "exchange_rate.policy",
+	"log-level":        "log.level",
+	"trace-exporter":   "tracing.exporter",
+}
+
+// Load reads the configuration from, in increasing order of precedence, the defaults,
+// the file given by the --config flag, the WEX_* environment variables and the command line flags.
+func Load(args []string) (*Config, error) {
+	return LoadFlagSet(pflag.NewFlagSet("wex", pflag.ContinueOnError), args)
+}
+
+// LoadFlagSet reads the configuration as in Load, adding the configuration flags to a flag set
+// that may already define flags of its own, which are parsed along with them.
+func LoadFlagSet(fs *pflag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", "", "path of a YAML, JSON or TOML configuration file")
+	fs.String("listen-addr", "", "address the HTTP server listens on")
+	fs.String("db-driver", "", "database driver: sqlite or postgres")
+	fs.String("db-dsn", "", "database data source name")
+	fs.Bool("auto-migrate", true, "apply the pending database migrations on startup")
+	fs.String("treasury-url", "", "base URL of the Treasury Reporting Rates of Exchange API")
+	fs.Duration("treasury-timeout", 0, "budget of each call to the Treasury API")
+	fs.Duration("http-timeout", 0, "timeout of requests to external APIs")
+	fs.Int("lookback-months", 0, "months before the transaction date an exchange rate may be used")
+	fs.String("rate-policy", "", "default rate selection policy: on_or_before, nearest or quarter_start")
+	fs.String("log-level", "", "log level: debug, info, warn or error")
+	fs.String("trace-exporter", "", "trace exporter: none, stdout or otlp")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	for name, key := range flags {
+		if err := v.BindPFlag(key, fs.Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToRateOverridesHook,
+	))); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks if the configuration is valid.
+func (c *Config) Validate() error {
+	if strings.TrimSpace(c.Server.ListenAddr) == "" {
+		return errors.New("server.listen_addr is required")
+	}
+
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("%s must be positive", timeout.key)
+		}
+	}
+
+	if c.Database.Driver != database.DriverSQLite && c.Database.Driver != database.DriverPostgres {
+		return fmt.Errorf("database.driver must be %s or %s, got %q", database.DriverSQLite, database.DriverPostgres, c.Database.Driver)
+	}
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		return errors.New("database.dsn is required")
+	}
+
+	u, err := url.Parse(c.Treasury.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("treasury.base_url must be an absolute http or https URL, got %q", c.Treasury.BaseURL)
+	}
+
+	if c.Treasury.CallTimeout <= 0 {
+		return errors.New("treasury.call_timeout must be positive")
+	}
+
+	if c.Treasury.Retry.MaxAttempts < 1 {
+		return errors.New("treasury.retry.max_attempts must be at least 1")
+	}
+
+	if c.Treasury.Retry.BaseDelay <= 0 || c.Treasury.Retry.MaxDelay < c.Treasury.Retry.BaseDelay {
+		return errors.New("treasury.retry.base_delay must be positive and not greater than treasury.retry.max_delay")
+	}
+
+	if c.Treasury.Breaker.FailureThreshold < 1 {
+		return errors.New("treasury.breaker.failure_threshold must be at least 1")
+	}
+
+	if c.Treasury.Breaker.OpenTimeout <= 0 {
+		return errors.New("treasury.breaker.open_timeout must be positive")
+	}
+
+	if c.HTTPClient.Timeout <= 0 {
+		return errors.New("http_client.timeout must be positive")
+	}
+
+	if c.ExchangeRate.LookbackMonths < 1 || c.ExchangeRate.LookbackMonths > gateway.MaxLookbackMonths {
+		return fmt.Errorf("exchange_rate.lookback_months must be between 1 and %d", gateway.MaxLookbackMonths)
+	}
+
+	if _, err := gateway.ParsePolicy(c.ExchangeRate.Policy); err != nil {
+		return fmt.Errorf("exchange_rate.policy: %w", err)
+	}
+
+	if c.ExchangeRate.Cache.Size < 0 {
+		return errors.New("exchange_rate.cache.size must not be negative")
+	}
+
+	if c.ExchangeRate.Cache.TTL <= 0 {
+		return errors.New("exchange_rate.cache.ttl must be positive")
+	}
+
+	if c.ExchangeRate.Cache.NegativeTTL < 0 {
+		return errors.New("exchange_rate.cache.negative_ttl must not be negative")
+	}
+
+	if c.RateSync.Interval < 0 {
+		return errors.New("rate_sync.interval must not be negative")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		return fmt.Errorf("log.level must be one of debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		return fmt.Errorf("tracing.exporter must be one of none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+
+	if c.Tracing.Endpoint != "" {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing.endpoint must be an absolute http or https URL, got %q", c.Tracing.Endpoint)
+		}
+	}
+
+	if strings.TrimSpace(c.Tracing.ServiceName) == "" {
+		return errors.New("tracing.service_name is required")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// stringToRateOverridesHook decodes exchange rate overrides given as a string,
+// e.g. "Canada-Dollar=1.35,Mexico-Peso=17.1", as environment variables are.
+func stringToRateOverridesHook(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+
+	return parseRateOverrides(data.(string))
+}
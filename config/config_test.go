@@ -0,0 +1,230 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"golang.org/x/exp/slog"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	got, gotErr := Load(nil)
+	assert.NoError(t, gotErr)
+
+	want := &Config{
+		Server: ServerConfig{
+			ListenAddr:        ":8082",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{Driver: "sqlite", DSN: "wex.db", AutoMigrate: true},
+		Treasury: TreasuryConfig{
+			BaseURL:     gateway.DefaultBaseURL,
+			CallTimeout: 5 * time.Second,
+			Retry:       RetryConfig{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second},
+			Breaker:     BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		},
+		HTTPClient: HTTPClientConfig{Timeout: 10 * time.Second},
+		ExchangeRate: ExchangeRateConfig{
+			Providers:      []string{},
+			Overrides:      map[string]string{},
+			LookbackMonths: gateway.DefaultLookbackMonths,
+			Policy:         "on_or_before",
+			Cache:          CacheConfig{Size: 10000, TTL: time.Hour, NegativeTTL: 5 * time.Minute},
+		},
+		RateSync: RateSyncConfig{Interval: 24 * time.Hour},
+		Log:      LogConfig{Level: "info"},
+		Tracing:  TracingConfig{Exporter: "none", ServiceName: "wex", SampleRatio: 1},
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, slog.LevelInfo, got.Log.SlogLevel())
+	assert.Equal(t, gateway.PolicyOnOrBefore, got.ExchangeRate.RatePolicy())
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+server:
+  listen_addr: ":9000"
+database:
+  dsn: file.db
+http_client:
+  timeout: 3s
+exchange_rate:
+  providers: [static, treasury]
+  overrides:
+    Canada-Dollar: "1.35"
+log:
+  level: warn
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WEX_DATABASE_DSN", "env.db")
+	t.Setenv("WEX_RATE_SYNC_INTERVAL", "0s")
+	t.Setenv("WEX_LOG_LEVEL", "debug")
+
+	got, gotErr := Load([]string{"--config", path, "--log-level", "error", "--lookback-months", "12", "--auto-migrate=false"})
+	assert.NoError(t, gotErr)
+
+	assert.Equal(t, ":9000", got.Server.ListenAddr)
+	assert.Equal(t, "env.db", got.Database.DSN)
+	assert.False(t, got.Database.AutoMigrate)
+	assert.Equal(t, 3*time.Second, got.HTTPClient.Timeout)
+	assert.Equal(t, []string{"static", "treasury"}, got.ExchangeRate.Providers)
+	assert.Equal(t, map[string]string{"canada-dollar": "1.35"}, got.ExchangeRate.Overrides)
+	assert.Equal(t, 12, got.ExchangeRate.LookbackMonths)
+	assert.Equal(t, time.Duration(0), got.RateSync.Interval)
+	assert.Equal(t, "error", got.Log.Level)
+}
+
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("WEX_EXCHANGE_RATE_PROVIDERS", "local,file")
+	t.Setenv("WEX_EXCHANGE_RATE_OVERRIDES", "Canada-Dollar=1.35, Euro Zone-Euro=0.92")
+	t.Setenv("WEX_TREASURY_BASE_URL", "http://localhost:9090/")
+	t.Setenv("WEX_EXCHANGE_RATE_POLICY", "Nearest")
+
+	got, gotErr := Load(nil)
+	assert.NoError(t, gotErr)
+
+	assert.Equal(t, gateway.PolicyNearest, got.ExchangeRate.RatePolicy())
+	assert.Equal(t, []string{"local", "file"}, got.ExchangeRate.Providers)
+	assert.Equal(t, map[string]string{"Canada-Dollar": "1.35", "Euro Zone-Euro": "0.92"}, got.ExchangeRate.Overrides)
+	assert.Equal(t, "http://localhost:9090/", got.Treasury.BaseURL)
+}
+
+func TestLoadFlagSet(t *testing.T) {
+	fs := pflag.NewFlagSet("wexctl", pflag.ContinueOnError)
+	currency := fs.String("currency", "", "")
+
+	got, gotErr := LoadFlagSet(fs, []string{"--currency", "CAD", "--db-dsn", "file.db", "extra"})
+	assert.NoError(t, gotErr)
+
+	assert.Equal(t, "CAD", *currency)
+	assert.Equal(t, "file.db", got.Database.DSN)
+	assert.Equal(t, []string{"extra"}, fs.Args())
+}
+
+func TestSetupServer(t *testing.T) {
+	cfg, err := Load([]string{"--listen-addr", ":9000"})
+	assert.NoError(t, err)
+
+	got := SetupServer(cfg, http.NotFoundHandler())
+	assert.Equal(t, ":9000", got.Addr)
+	assert.Equal(t, 5*time.Second, got.ReadHeaderTimeout)
+	assert.Equal(t, 15*time.Second, got.ReadTimeout)
+	assert.Equal(t, 60*time.Second, got.WriteTimeout)
+	assert.Equal(t, 120*time.Second, got.IdleTimeout)
+}
+
+func TestLoad_Error(t *testing.T) {
+	testCases := map[string]struct {
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		"unknown flag": {
+			args:    []string{"--port", "80"},
+			wantErr: "unknown flag",
+		},
+		"missing config file": {
+			args:    []string{"--config", "missing.yaml"},
+			wantErr: "failed to read config file",
+		},
+		"empty listen address": {
+			env:     map[string]string{"WEX_SERVER_LISTEN_ADDR": " "},
+			wantErr: "server.listen_addr",
+		},
+		"invalid server timeout": {
+			env:     map[string]string{"WEX_SERVER_WRITE_TIMEOUT": "0s"},
+			wantErr: "server.write_timeout",
+		},
+		"unknown database driver": {
+			args:    []string{"--db-driver", "mysql"},
+			wantErr: "database.driver",
+		},
+		"relative treasury url": {
+			args:    []string{"--treasury-url", "api.fiscaldata.treasury.gov"},
+			wantErr: "treasury.base_url",
+		},
+		"invalid treasury timeout": {
+			args:    []string{"--treasury-timeout", "0s"},
+			wantErr: "treasury.call_timeout",
+		},
+		"invalid retry attempts": {
+			env:     map[string]string{"WEX_TREASURY_RETRY_MAX_ATTEMPTS": "0"},
+			wantErr: "treasury.retry.max_attempts",
+		},
+		"retry base delay above max delay": {
+			env:     map[string]string{"WEX_TREASURY_RETRY_BASE_DELAY": "10s"},
+			wantErr: "treasury.retry.base_delay",
+		},
+		"invalid breaker threshold": {
+			env:     map[string]string{"WEX_TREASURY_BREAKER_FAILURE_THRESHOLD": "0"},
+			wantErr: "treasury.breaker.failure_threshold",
+		},
+		"invalid timeout": {
+			args:    []string{"--http-timeout", "-1s"},
+			wantErr: "http_client.timeout",
+		},
+		"invalid lookback": {
+			args:    []string{"--lookback-months", "200"},
+			wantErr: "exchange_rate.lookback_months",
+		},
+		"negative cache size": {
+			env:     map[string]string{"WEX_EXCHANGE_RATE_CACHE_SIZE": "-1"},
+			wantErr: "exchange_rate.cache.size",
+		},
+		"invalid cache ttl": {
+			env:     map[string]string{"WEX_EXCHANGE_RATE_CACHE_TTL": "0s"},
+			wantErr: "exchange_rate.cache.ttl",
+		},
+		"invalid rate policy": {
+			args:    []string{"--rate-policy", "latest"},
+			wantErr: "exchange_rate.policy",
+		},
+		"invalid log level": {
+			args:    []string{"--log-level", "verbose"},
+			wantErr: "log.level",
+		},
+		"unknown trace exporter": {
+			args:    []string{"--trace-exporter", "jaeger"},
+			wantErr: "tracing.exporter",
+		},
+		"relative trace endpoint": {
+			env:     map[string]string{"WEX_TRACING_ENDPOINT": "localhost:4318"},
+			wantErr: "tracing.endpoint",
+		},
+		"invalid sample ratio": {
+			env:     map[string]string{"WEX_TRACING_SAMPLE_RATIO": "1.5"},
+			wantErr: "tracing.sample_ratio",
+		},
+		"invalid overrides": {
+			env:     map[string]string{"WEX_EXCHANGE_RATE_OVERRIDES": "Canada-Dollar"},
+			wantErr: "invalid exchange rate override",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			got, gotErr := Load(tc.args)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+			assert.Nil(t, got)
+		})
+	}
+}
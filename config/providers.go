@@ -0,0 +1,72 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/vickiliou/challenge-wex/internal/exchangerate"
+	"github.com/vickiliou/challenge-wex/internal/repository"
+)
+
+// SetupExchangeRates creates the chain of exchange rate providers used to convert transactions.
+// Unless an order is configured, overrides are tried first, then the local rate store, the Treasury API
+// and finally the rate file. The operations of the local rate store are reported to observer unless it is nil.
+func SetupExchangeRates(cfg ExchangeRateConfig, db *sql.DB, dialect repository.Dialect, observer repository.QueryObserver, treasury exchangerate.Provider) (*exchangerate.Chain, error) {
+	registry := exchangerate.NewRegistry(
+		exchangerate.NewProvider(exchangerate.ProviderLocal, repository.NewExchangeRateRepository(db, dialect, observer)),
+		treasury,
+	)
+
+	order := []string{exchangerate.ProviderLocal, exchangerate.ProviderTreasury}
+
+	if len(cfg.Overrides) > 0 {
+		p, err := exchangerate.NewStaticProvider(cfg.Overrides)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(p)
+		order = append([]string{exchangerate.ProviderStatic}, order...)
+	}
+
+	if cfg.File != "" {
+		p, err := exchangerate.NewFileProvider(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(p)
+		order = append(order, exchangerate.ProviderFile)
+	}
+
+	if len(cfg.Providers) > 0 {
+		order = cfg.Providers
+	}
+
+	return registry.Chain(order...)
+}
+
+// parseRateOverrides parses a list of overrides in the "Country-Currency=rate" form.
+func parseRateOverrides(s string) (map[string]string, error) {
+	rates := make(map[string]string)
+
+	for _, item := range splitList(s) {
+		desc, rate, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid exchange rate override %q", item)
+		}
+		rates[strings.TrimSpace(desc)] = strings.TrimSpace(rate)
+	}
+
+	return rates, nil
+}
+
+// splitList splits a comma-separated list, ignoring blank items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
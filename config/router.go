@@ -6,27 +6,117 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/exchangerate"
 	"github.com/vickiliou/challenge-wex/internal/gateway"
 	"github.com/vickiliou/challenge-wex/internal/httphandler"
+	"github.com/vickiliou/challenge-wex/internal/metrics"
+	"github.com/vickiliou/challenge-wex/internal/ratecache"
+	"github.com/vickiliou/challenge-wex/internal/ratesync"
 	"github.com/vickiliou/challenge-wex/internal/repository"
+	"github.com/vickiliou/challenge-wex/internal/resilience"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
 	"github.com/vickiliou/challenge-wex/internal/transaction"
 )
 
-// SetupRouter creates and configures the HTTP router for the application.
-func SetupRouter(db *sql.DB) *chi.Mux {
+// SetupRouter creates and configures the HTTP router for the application, tracing its requests, recording them
+// in m and serving m on /metrics. Exchange rates are fetched from the Treasury API through gw.
+func SetupRouter(cfg *Config, db *sql.DB, m *metrics.Metrics, gw *gateway.Gateway) (*chi.Mux, error) {
 	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Use(m.Middleware)
 
-	gw := gateway.NewGateway(&http.Client{})
-	repo := repository.NewRepository(db)
-	svc := transaction.NewService(repo, gw, uuid.NewString)
-	h := httphandler.NewHandler(svc)
+	svc, err := SetupService(cfg, db, m, gw)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := repository.NewIdempotencyRepository(db, cfg.Database.Dialect(), m)
+	h := httphandler.NewHandler(svc, keys)
+	ch := httphandler.NewCurrencyHandler(currency.NewCatalog(repository.NewExchangeRateRepository(db, cfg.Database.Dialect(), m)))
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	r.Method(http.MethodGet, "/metrics", m.Handler())
 
 	r.Post("/v1/transactions", h.Store)
+	r.Get("/v1/transactions", h.List)
+	r.Post("/v1/transactions/import", h.Import)
+	r.Get("/v1/transactions/export", h.Export)
+	r.Post("/v1/transactions/conversions", h.Convert)
 	r.Get("/v1/transactions/{id}", h.Retrieve)
+	r.Patch("/v1/transactions/{id}", h.Update)
+	r.Delete("/v1/transactions/{id}", h.Delete)
+	r.Get("/v1/transactions/{id}/history", h.History)
+	r.Get("/v1/reports/summary", h.Summary)
+	r.Get("/v1/currencies", ch.List)
+
+	return r, nil
+}
+
+// SetupService creates the transaction service, converting amounts with the configured exchange rate providers,
+// the Treasury API being called through gw. Its database operations and rate cache are recorded in m.
+func SetupService(cfg *Config, db *sql.DB, m *metrics.Metrics, gw *gateway.Gateway) (*transaction.Service, error) {
+	rates, err := SetupExchangeRates(cfg.ExchangeRate, db, cfg.Database.Dialect(), m, setupTreasuryRates(cfg, m, gw))
+	if err != nil {
+		return nil, err
+	}
+
+	repo := repository.NewRepository(db, cfg.Database.Dialect(), m)
+
+	return transaction.NewService(repo, rates, uuid.NewString, transaction.RateSelection{
+		Policy:         cfg.ExchangeRate.RatePolicy(),
+		LookbackMonths: cfg.ExchangeRate.LookbackMonths,
+	}), nil
+}
+
+// SetupServer creates the HTTP server serving the handler with the configured timeouts.
+func SetupServer(cfg *Config, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           h,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+}
+
+// SetupRateSync creates the syncer that keeps the local exchange rate store up to date from the Treasury API
+// through gw, recording its database operations in m.
+func SetupRateSync(cfg *Config, db *sql.DB, m *metrics.Metrics, gw *gateway.Gateway) *ratesync.Syncer {
+	rates := repository.NewExchangeRateRepository(db, cfg.Database.Dialect(), m)
+
+	return ratesync.NewSyncer(gw, rates)
+}
+
+// setupTreasuryRates creates the Treasury exchange rate provider, caching rates in memory unless the cache size is zero.
+func setupTreasuryRates(cfg *Config, m *metrics.Metrics, gw *gateway.Gateway) exchangerate.Provider {
+	c := cfg.ExchangeRate.Cache
+	if c.Size == 0 {
+		return exchangerate.NewProvider(exchangerate.ProviderTreasury, gw)
+	}
+
+	cache := ratecache.New(gw, c.Size, c.TTL, c.NegativeTTL)
+	m.RegisterRateCache(cache)
+
+	return exchangerate.NewProvider(exchangerate.ProviderTreasury, cache)
+}
+
+// SetupGateway creates the Treasury API gateway, retrying failed calls behind a circuit breaker, and recording
+// its calls in m. Every attempt is traced, propagating the trace to the API. A process creates a single gateway,
+// shared by everything calling the API, so that the breaker opens for all of them at once.
+func SetupGateway(cfg *Config, m *metrics.Metrics) *gateway.Gateway {
+	client := resilience.NewClient(
+		&http.Client{Timeout: cfg.HTTPClient.Timeout, Transport: tracing.NewTransport(http.DefaultTransport)},
+		resilience.RetryPolicy{
+			MaxAttempts: cfg.Treasury.Retry.MaxAttempts,
+			BaseDelay:   cfg.Treasury.Retry.BaseDelay,
+			MaxDelay:    cfg.Treasury.Retry.MaxDelay,
+		},
+		resilience.NewBreaker(cfg.Treasury.Breaker.FailureThreshold, cfg.Treasury.Breaker.OpenTimeout),
+	)
 
-	return r
+	return gateway.NewGateway(m.InstrumentClient(client), cfg.Treasury.BaseURL, cfg.Treasury.CallTimeout)
 }
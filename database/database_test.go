@@ -0,0 +1,101 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup_AutoMigrate(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "wex.db")
+
+	_, err := Setup(DriverSQLite, dsn, false)
+	assert.ErrorIs(t, err, ErrNotMigrated)
+
+	db, err := Open(DriverSQLite, dsn)
+	require.NoError(t, err)
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables))
+	assert.Zero(t, tables, "checking the version must not write to the database")
+	db.Close()
+
+	db, err = Setup(DriverSQLite, dsn, true)
+	require.NoError(t, err)
+	db.Close()
+
+	db, err = Setup(DriverSQLite, dsn, false)
+	require.NoError(t, err)
+	db.Close()
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "wex.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	var out bytes.Buffer
+
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateUp, &out))
+	assert.NoError(t, checkVersion(db, DriverSQLite))
+
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateDown, &out))
+	assert.Error(t, checkVersion(db, DriverSQLite))
+
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateUp, &out))
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateRedo, &out))
+	assert.NoError(t, checkVersion(db, DriverSQLite))
+
+	out.Reset()
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateStatus, &out))
+	assert.Contains(t, out.String(), "00001_create_transaction_table.sql")
+
+	err = Migrate(db, DriverSQLite, "drop", &out)
+	assert.ErrorIs(t, err, ErrUnknownMigrationCommand)
+
+	err = Migrate(db, "mysql", MigrateUp, &out)
+	assert.ErrorContains(t, err, "unsupported database driver")
+}
+
+func TestMigrate_TransactionDatesToUTC(t *testing.T) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "wex.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = setDialect(DriverSQLite)
+	require.NoError(t, err)
+	require.NoError(t, goose.UpTo(db, dialects[DriverSQLite].migrationsDir, 3))
+
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+	dates := map[string]time.Time{
+		"1": time.Date(2023, time.September, 21, 22, 30, 0, 0, saoPaulo),
+		"2": time.Date(2023, time.September, 21, 10, 0, 0, 123456789, saoPaulo),
+		"3": time.Date(2023, time.September, 21, 10, 0, 0, 0, time.UTC),
+	}
+	for id, date := range dates {
+		_, err := db.Exec(`INSERT INTO transactions (id, description, date, amount, currency) VALUES (?, 'food', ?, 100, 'USD')`, id, date)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, Migrate(db, DriverSQLite, MigrateUp, io.Discard))
+
+	want := map[string]string{
+		"1": "2023-09-22 01:30:00+00:00",
+		"2": "2023-09-21 13:00:00.123456789+00:00",
+		"3": "2023-09-21 10:00:00+00:00",
+	}
+	for id, wantDate := range want {
+		var got string
+		require.NoError(t, db.QueryRow(`SELECT CAST(date AS TEXT) FROM transactions WHERE id = ?`, id).Scan(&got))
+		assert.Equal(t, wantDate, got, id)
+
+		var date time.Time
+		require.NoError(t, db.QueryRow(`SELECT date FROM transactions WHERE id = ?`, id).Scan(&date))
+		assert.True(t, dates[id].Equal(date), id)
+	}
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS transactions (
+    id              TEXT            PRIMARY KEY,
+    description     VARCHAR(50)     NOT NULL,
+    date            TIMESTAMPTZ     NOT NULL,
+    amount          NUMERIC         NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE transactions;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN currency;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC USING amount / 100.0;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE transactions_minor_units (
+    id              TEXT            PRIMARY KEY,
+    description     VARCHAR(50)     NOT NULL,
+    date            DATE            NOT NULL,
+    amount          INTEGER         NOT NULL,
+    currency        CHAR(3)         NOT NULL DEFAULT 'USD'
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO transactions_minor_units (id, description, date, amount, currency)
+SELECT id, description, date, CAST(ROUND(amount * 100) AS INTEGER), 'USD' FROM transactions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE transactions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions_minor_units RENAME TO transactions;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE transactions_major_units (
+    id              TEXT            PRIMARY KEY,
+    description     VARCHAR(50)     NOT NULL,
+    date            DATE            NOT NULL,
+    amount          NUMERIC         NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO transactions_major_units (id, description, date, amount)
+SELECT id, description, date, amount / 100.0 FROM transactions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE transactions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions_major_units RENAME TO transactions;
+-- +goose StatementEnd
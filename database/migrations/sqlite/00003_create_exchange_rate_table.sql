@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    country_currency_desc   TEXT            NOT NULL,
+    record_date             DATE            NOT NULL,
+    exchange_rate           TEXT            NOT NULL,
+    PRIMARY KEY (country_currency_desc, record_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE exchange_rates;
+-- +goose StatementEnd
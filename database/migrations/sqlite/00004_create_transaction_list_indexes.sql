@@ -0,0 +1,28 @@
+-- Transaction dates were stored as text with the offset they were given with, e.g. 2023-09-21 10:00:00-03:00,
+-- while they are now always written in UTC. Rewrite the dates written before in UTC, keeping their fractional
+-- seconds, so that they are ordered and filtered consistently with the newer ones.
+
+-- +goose Up
+-- +goose StatementBegin
+UPDATE transactions
+SET date = datetime(substr(date, 1, 19) || substr(date, -6)) || substr(date, 20, length(date) - 25) || '+00:00'
+WHERE date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]*[+-][0-9][0-9]:[0-9][0-9]'
+  AND substr(date, -6) <> '+00:00';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_transactions_date_id ON transactions (date, id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_transactions_amount_id ON transactions (amount, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_amount_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_date_id;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key     VARCHAR(255)    PRIMARY KEY,
+    request_hash        CHAR(64)        NOT NULL,
+    status_code         INTEGER,
+    response_body       TEXT,
+    created_at          TIMESTAMP       NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN deleted_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS transaction_history (
+    id                  INTEGER         PRIMARY KEY AUTOINCREMENT,
+    transaction_id      TEXT            NOT NULL,
+    description         VARCHAR(50)     NOT NULL,
+    date                DATE            NOT NULL,
+    amount              INTEGER         NOT NULL,
+    currency            CHAR(3)         NOT NULL,
+    change              VARCHAR(10)     NOT NULL,
+    changed_by          VARCHAR(255)    NOT NULL,
+    changed_at          TIMESTAMP       NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_transaction_history_transaction_id ON transaction_history (transaction_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE transaction_history;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,111 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// RateRange represents the record dates of the exchange rates stored for a country currency.
+type RateRange struct {
+	CountryCurrencyDesc string
+	From                time.Time
+	To                  time.Time
+}
+
+type rateStore interface {
+	RateRanges(ctx context.Context) ([]RateRange, error)
+}
+
+// CatalogResponse represents the currencies transactions can be converted to.
+type CatalogResponse struct {
+	Data []CatalogEntry `json:"data"`
+}
+
+// CatalogEntry represents a country currency of the Treasury dataset and the date range of its stored exchange rates.
+type CatalogEntry struct {
+	CountryCurrencyDesc string `json:"country_currency_desc"`
+	Country             string `json:"country"`
+	Currency            string `json:"currency"`
+	ISOCountry          string `json:"iso_country,omitempty"`
+	ISOCurrency         string `json:"iso_currency,omitempty"`
+	RatesFrom           string `json:"rates_from,omitempty"`
+	RatesTo             string `json:"rates_to,omitempty"`
+}
+
+// Catalog lists the supported target currencies.
+type Catalog struct {
+	store rateStore
+}
+
+// NewCatalog creates a new catalog backed by the local exchange rate store.
+func NewCatalog(store rateStore) *Catalog {
+	return &Catalog{
+		store: store,
+	}
+}
+
+// List returns the currencies of the mapping table merged with the ones of the local exchange rate store,
+// sorted by country currency description. Currencies without stored rates have no date range.
+func (c *Catalog) List(ctx context.Context) (*CatalogResponse, error) {
+	ranges, err := c.store.RateRanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	entries := make(map[string]CatalogEntry, len(table.currencies)+len(ranges))
+	for _, cur := range table.currencies {
+		entries[cur.Desc()] = newCatalogEntry(cur)
+	}
+
+	for _, r := range ranges {
+		entry, ok := entries[r.CountryCurrencyDesc]
+		if !ok {
+			entry = newCatalogEntry(splitDesc(r.CountryCurrencyDesc))
+			entry.CountryCurrencyDesc = r.CountryCurrencyDesc
+		}
+
+		entry.RatesFrom = r.From.Format(dateFormat)
+		entry.RatesTo = r.To.Format(dateFormat)
+		entries[r.CountryCurrencyDesc] = entry
+	}
+
+	res := &CatalogResponse{
+		Data: make([]CatalogEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		res.Data = append(res.Data, entry)
+	}
+
+	sort.Slice(res.Data, func(i, j int) bool {
+		return res.Data[i].CountryCurrencyDesc < res.Data[j].CountryCurrencyDesc
+	})
+
+	return res, nil
+}
+
+// newCatalogEntry creates the catalog entry of a currency.
+func newCatalogEntry(c Currency) CatalogEntry {
+	return CatalogEntry{
+		CountryCurrencyDesc: c.Desc(),
+		Country:             c.TreasuryCountry,
+		Currency:            c.TreasuryCurrency,
+		ISOCountry:          c.Country,
+		ISOCurrency:         c.Code,
+	}
+}
+
+// splitDesc splits an unmapped Treasury country currency description, e.g. "Canada-Dollar".
+// Country names may contain hyphens but currency names do not, so the last hyphen separates them.
+func splitDesc(desc string) Currency {
+	i := strings.LastIndex(desc, "-")
+	if i < 0 {
+		return Currency{TreasuryCountry: desc}
+	}
+
+	return Currency{TreasuryCountry: desc[:i], TreasuryCurrency: desc[i+1:]}
+}
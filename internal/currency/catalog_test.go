@@ -0,0 +1,86 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubRateStore struct {
+	rateRanges func(ctx context.Context) ([]RateRange, error)
+}
+
+func (s *stubRateStore) RateRanges(ctx context.Context) ([]RateRange, error) {
+	return s.rateRanges(ctx)
+}
+
+func TestCatalog_List(t *testing.T) {
+	mockStore := &stubRateStore{
+		rateRanges: func(ctx context.Context) ([]RateRange, error) {
+			return []RateRange{
+				{
+					CountryCurrencyDesc: "Canada-Dollar",
+					From:                time.Date(2001, time.March, 31, 0, 0, 0, 0, time.UTC),
+					To:                  time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					CountryCurrencyDesc: "Timor-Leste-Dollar",
+					From:                time.Date(2020, time.March, 31, 0, 0, 0, 0, time.UTC),
+					To:                  time.Date(2020, time.March, 31, 0, 0, 0, 0, time.UTC),
+				},
+			}, nil
+		},
+	}
+
+	got, gotErr := NewCatalog(mockStore).List(context.Background())
+	assert.NoError(t, gotErr)
+
+	byDesc := make(map[string]CatalogEntry, len(got.Data))
+	for _, entry := range got.Data {
+		byDesc[entry.CountryCurrencyDesc] = entry
+	}
+
+	assert.Len(t, got.Data, len(All())+1)
+	assert.Equal(t, CatalogEntry{
+		CountryCurrencyDesc: "Canada-Dollar",
+		Country:             "Canada",
+		Currency:            "Dollar",
+		ISOCountry:          "CA",
+		ISOCurrency:         "CAD",
+		RatesFrom:           "2001-03-31",
+		RatesTo:             "2023-09-30",
+	}, byDesc["Canada-Dollar"])
+	assert.Equal(t, CatalogEntry{
+		CountryCurrencyDesc: "Timor-Leste-Dollar",
+		Country:             "Timor-Leste",
+		Currency:            "Dollar",
+		RatesFrom:           "2020-03-31",
+		RatesTo:             "2020-03-31",
+	}, byDesc["Timor-Leste-Dollar"])
+	assert.Equal(t, CatalogEntry{
+		CountryCurrencyDesc: "Brazil-Real",
+		Country:             "Brazil",
+		Currency:            "Real",
+		ISOCountry:          "BR",
+		ISOCurrency:         "BRL",
+	}, byDesc["Brazil-Real"])
+
+	for i := 1; i < len(got.Data); i++ {
+		assert.Less(t, got.Data[i-1].CountryCurrencyDesc, got.Data[i].CountryCurrencyDesc)
+	}
+}
+
+func TestCatalog_List_Error(t *testing.T) {
+	mockStore := &stubRateStore{
+		rateRanges: func(ctx context.Context) ([]RateRange, error) {
+			return nil, errors.New("database is locked")
+		},
+	}
+
+	got, gotErr := NewCatalog(mockStore).List(context.Background())
+	assert.ErrorContains(t, gotErr, "database is locked")
+	assert.Nil(t, got)
+}
@@ -0,0 +1,175 @@
+package currency
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed currencies.csv
+var currenciesCSV []byte
+
+var (
+	// ErrUnsupportedCurrency indicates that a currency code is not in the mapping table or not used in the requested country.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+	// ErrAmbiguousCurrency indicates that a currency code is used by several countries and no country was given.
+	ErrAmbiguousCurrency = errors.New("ambiguous currency")
+)
+
+// Currency represents a currency of the Treasury dataset along with its ISO codes.
+type Currency struct {
+	Code             string // ISO 4217 currency code, empty if not mapped
+	Country          string // ISO 3166-1 alpha-2 country code, empty for currency unions such as the Euro Zone
+	TreasuryCountry  string // country as spelled by the Treasury dataset, e.g. "Euro Zone"
+	TreasuryCurrency string // currency as spelled by the Treasury dataset, e.g. "Euro"
+}
+
+// Desc returns the Treasury country currency description, e.g. "Canada-Dollar".
+func (c Currency) Desc() string {
+	return c.TreasuryCountry + "-" + c.TreasuryCurrency
+}
+
+// table is the embedded mapping between ISO codes and Treasury descriptors.
+var table = mustLoad(currenciesCSV)
+
+type mapping struct {
+	currencies []Currency
+	byCode     map[string][]Currency
+	byCountry  map[string]string
+	byDesc     map[string]Currency
+}
+
+// mustLoad parses the mapping table, panicking if it is malformed since it is embedded at build time.
+func mustLoad(data []byte) *mapping {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("currency: invalid mapping table: %v", err))
+	}
+
+	m := &mapping{
+		byCode:    make(map[string][]Currency),
+		byCountry: make(map[string]string),
+		byDesc:    make(map[string]Currency),
+	}
+
+	for _, record := range records[1:] {
+		c := Currency{
+			Code:             record[0],
+			Country:          record[1],
+			TreasuryCountry:  record[2],
+			TreasuryCurrency: record[3],
+		}
+
+		m.currencies = append(m.currencies, c)
+		m.byCode[c.Code] = append(m.byCode[c.Code], c)
+		m.byDesc[c.Desc()] = c
+		if c.Country != "" {
+			m.byCountry[c.Country] = c.TreasuryCountry
+		}
+	}
+
+	return m
+}
+
+// All returns every currency of the mapping table.
+func All() []Currency {
+	return append([]Currency(nil), table.currencies...)
+}
+
+// ByDesc returns the currency of a Treasury country currency description, e.g. "Canada-Dollar".
+func ByDesc(desc string) (Currency, bool) {
+	c, ok := table.byDesc[desc]
+	return c, ok
+}
+
+// Resolve finds the Treasury currency requested by a country and a currency.
+//
+// The currency is either an ISO 4217 code, e.g. "CAD", or a Treasury currency name, e.g. "Dollar".
+// The country is either an ISO 3166-1 alpha-2 code, e.g. "CA", or a Treasury country name, e.g. "Canada".
+// With an ISO currency code the country is optional, unless the code is used by several countries
+// and none of them is a currency union, such as the Euro Zone for EUR.
+func Resolve(country, currency string) (Currency, error) {
+	country = strings.TrimSpace(country)
+	currency = strings.TrimSpace(currency)
+
+	if currency == "" {
+		return Currency{}, errors.New("currency is required")
+	}
+
+	if isCode(currency) {
+		if candidates, ok := table.byCode[strings.ToUpper(currency)]; ok {
+			return resolveCode(country, strings.ToUpper(currency), candidates)
+		}
+
+		if currency == strings.ToUpper(currency) {
+			return Currency{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+		}
+	}
+
+	if country == "" {
+		return Currency{}, errors.New("currency country is required")
+	}
+
+	if name, ok := table.byCountry[strings.ToUpper(country)]; ok && isCountryCode(country) {
+		country = name
+	}
+
+	c := Currency{TreasuryCountry: country, TreasuryCurrency: currency}
+	if mapped, ok := table.byDesc[c.Desc()]; ok {
+		return mapped, nil
+	}
+
+	return c, nil
+}
+
+// resolveCode picks the currency of the country among the ones using the ISO code.
+func resolveCode(country, code string, candidates []Currency) (Currency, error) {
+	if country == "" {
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+
+		countries := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			if c.Country == "" {
+				return c, nil
+			}
+			countries = append(countries, c.Country)
+		}
+		sort.Strings(countries)
+
+		return Currency{}, fmt.Errorf("%w: %s is used by %s, country is required", ErrAmbiguousCurrency, code, strings.Join(countries, ", "))
+	}
+
+	for _, c := range candidates {
+		if strings.EqualFold(c.Country, country) || strings.EqualFold(c.TreasuryCountry, country) {
+			return c, nil
+		}
+	}
+
+	return Currency{}, fmt.Errorf("%w: %s is not used in %s", ErrUnsupportedCurrency, code, country)
+}
+
+// isCode reports whether s has the form of an ISO 4217 code.
+func isCode(s string) bool {
+	return len(s) == 3 && isLetters(s)
+}
+
+// isCountryCode reports whether s has the form of an ISO 3166-1 alpha-2 code.
+func isCountryCode(s string) bool {
+	return len(s) == 2 && isLetters(s)
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
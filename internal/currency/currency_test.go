@@ -0,0 +1,122 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	testCases := map[string]struct {
+		country  string
+		currency string
+		want     Currency
+	}{
+		"iso currency code": {
+			currency: "CAD",
+			want:     Currency{Code: "CAD", Country: "CA", TreasuryCountry: "Canada", TreasuryCurrency: "Dollar"},
+		},
+		"lowercase iso currency code": {
+			currency: "cad",
+			want:     Currency{Code: "CAD", Country: "CA", TreasuryCountry: "Canada", TreasuryCurrency: "Dollar"},
+		},
+		"currency union": {
+			currency: "EUR",
+			want:     Currency{Code: "EUR", TreasuryCountry: "Euro Zone", TreasuryCurrency: "Euro"},
+		},
+		"iso currency and country codes": {
+			country:  "DE",
+			currency: "EUR",
+			want:     Currency{Code: "EUR", Country: "DE", TreasuryCountry: "Germany", TreasuryCurrency: "Euro"},
+		},
+		"iso currency code and treasury country": {
+			country:  "Benin",
+			currency: "XOF",
+			want:     Currency{Code: "XOF", Country: "BJ", TreasuryCountry: "Benin", TreasuryCurrency: "Cfa Franc"},
+		},
+		"treasury names": {
+			country:  "Canada",
+			currency: "Dollar",
+			want:     Currency{Code: "CAD", Country: "CA", TreasuryCountry: "Canada", TreasuryCurrency: "Dollar"},
+		},
+		"treasury name with three letters": {
+			country:  "Korea",
+			currency: "Won",
+			want:     Currency{Code: "KRW", Country: "KR", TreasuryCountry: "Korea", TreasuryCurrency: "Won"},
+		},
+		"iso country code and treasury currency": {
+			country:  "mx",
+			currency: "Peso",
+			want:     Currency{Code: "MXN", Country: "MX", TreasuryCountry: "Mexico", TreasuryCurrency: "Peso"},
+		},
+		"unmapped treasury names": {
+			country:  "Atlantis",
+			currency: "Pearl",
+			want:     Currency{TreasuryCountry: "Atlantis", TreasuryCurrency: "Pearl"},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := Resolve(tc.country, tc.currency)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestResolve_Error(t *testing.T) {
+	testCases := map[string]struct {
+		country  string
+		currency string
+		wantErr  string
+	}{
+		"empty currency": {
+			country: "Canada",
+			wantErr: "currency is required",
+		},
+		"treasury currency without country": {
+			currency: "Dollar",
+			wantErr:  "currency country is required",
+		},
+		"unknown iso currency code": {
+			currency: "XYZ",
+			wantErr:  ErrUnsupportedCurrency.Error(),
+		},
+		"currency not used in country": {
+			country:  "US",
+			currency: "CAD",
+			wantErr:  "CAD is not used in US",
+		},
+		"ambiguous currency code": {
+			currency: "XCD",
+			wantErr:  "XCD is used by AG, DM, GD, KN, LC, country is required",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			_, gotErr := Resolve(tc.country, tc.currency)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestByDesc(t *testing.T) {
+	got, ok := ByDesc("Euro Zone-Euro")
+	assert.True(t, ok)
+	assert.Equal(t, "EUR", got.Code)
+
+	_, ok = ByDesc("Atlantis-Pearl")
+	assert.False(t, ok)
+}
+
+func TestTable(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for _, c := range All() {
+		assert.Len(t, c.Code, 3, c.Desc())
+		assert.False(t, seen[c.Desc()], "duplicate %s", c.Desc())
+		seen[c.Desc()] = true
+	}
+}
@@ -0,0 +1,158 @@
+package exchangerate
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+const dateFormat = "2006-01-02"
+
+// fileHeader is the expected header of a CSV rate file, matching the fields of the Treasury dataset.
+var fileHeader = []string{"country_currency_desc", "exchange_rate", "record_date"}
+
+// FileProvider supplies exchange rates loaded from a local CSV or JSON file.
+// The file has the same fields as the Treasury dataset: country_currency_desc, exchange_rate and record_date.
+type FileProvider struct {
+	// rates holds the rates of each country currency description, most recent first.
+	rates map[string][]gateway.CurrencyExchangeRate
+}
+
+// NewFileProvider loads the rates of the file at path. The format is chosen by the .csv or .json extension.
+func NewFileProvider(path string) (*FileProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exchange rate file: %w", err)
+	}
+	defer f.Close()
+
+	var rates []gateway.CurrencyExchangeRate
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rates, err = readCSV(f)
+	case ".json":
+		rates, err = readJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rate file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rate file: %w", err)
+	}
+
+	return newFileProvider(rates)
+}
+
+// newFileProvider validates the rates and indexes them by country currency description.
+func newFileProvider(rates []gateway.CurrencyExchangeRate) (*FileProvider, error) {
+	p := &FileProvider{
+		rates: make(map[string][]gateway.CurrencyExchangeRate),
+	}
+
+	for i, rate := range rates {
+		if _, err := money.ParseRate(rate.ExchangeRate); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+
+		if _, err := time.Parse(dateFormat, rate.RecordDate); err != nil {
+			return nil, fmt.Errorf("record %d: invalid record date %q", i+1, rate.RecordDate)
+		}
+
+		p.rates[rate.CountryCurrencyDesc] = append(p.rates[rate.CountryCurrencyDesc], rate)
+	}
+
+	for _, list := range p.rates {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].RecordDate > list[j].RecordDate
+		})
+	}
+
+	return p, nil
+}
+
+// Name returns the name of the provider.
+func (p *FileProvider) Name() string {
+	return ProviderFile
+}
+
+// GetExchangeRate returns the rate of the file within the window of the transaction date
+// selected by the rate policy of the request.
+func (p *FileProvider) GetExchangeRate(_ context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	from, to := input.Window()
+	fromDate, toDate := from.Format(dateFormat), to.Format(dateFormat)
+
+	var candidates []gateway.CurrencyExchangeRate
+	for _, rate := range p.rates[input.CountryCurrencyDesc()] {
+		if rate.RecordDate > toDate {
+			continue
+		}
+
+		if rate.RecordDate < fromDate {
+			break
+		}
+
+		candidates = append(candidates, rate)
+	}
+
+	rate := input.Select(candidates)
+	if rate == nil {
+		return nil, httpresponse.ErrNoCurrencyConversion
+	}
+
+	return rate, nil
+}
+
+// readCSV reads rates from CSV with a header row.
+func readCSV(r io.Reader) ([]gateway.CurrencyExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(fileHeader)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range fileHeader {
+		if strings.TrimSpace(header[i]) != name {
+			return nil, fmt.Errorf("invalid header, want %s", strings.Join(fileHeader, ","))
+		}
+	}
+
+	var rates []gateway.CurrencyExchangeRate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, gateway.CurrencyExchangeRate{
+			CountryCurrencyDesc: strings.TrimSpace(record[0]),
+			ExchangeRate:        strings.TrimSpace(record[1]),
+			RecordDate:          strings.TrimSpace(record[2]),
+		})
+	}
+}
+
+// readJSON reads rates from a JSON array of objects.
+func readJSON(r io.Reader) ([]gateway.CurrencyExchangeRate, error) {
+	var rates []gateway.CurrencyExchangeRate
+	if err := json.NewDecoder(r).Decode(&rates); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
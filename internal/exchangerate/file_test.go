@@ -0,0 +1,154 @@
+package exchangerate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+const csvRates = `country_currency_desc,exchange_rate,record_date
+Brazil-Real,4.8,2023-06-30
+Brazil-Real,5.003,2023-09-30
+Canada-Dollar,1.323,2023-06-30
+`
+
+const jsonRates = `[
+	{"country_currency_desc": "Brazil-Real", "exchange_rate": "4.8", "record_date": "2023-06-30"},
+	{"country_currency_desc": "Brazil-Real", "exchange_rate": "5.003", "record_date": "2023-09-30"},
+	{"country_currency_desc": "Canada-Dollar", "exchange_rate": "1.323", "record_date": "2023-06-30"}
+]`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestFileProvider_GetExchangeRate(t *testing.T) {
+	testCases := map[string]struct {
+		file     string
+		content  string
+		date     time.Time
+		policy   gateway.RatePolicy
+		wantRate string
+	}{
+		"csv most recent rate": {
+			file:     "rates.csv",
+			content:  csvRates,
+			date:     time.Date(2023, time.October, 15, 0, 0, 0, 0, time.UTC),
+			wantRate: "5.003",
+		},
+		"csv rate on or before the date": {
+			file:     "rates.csv",
+			content:  csvRates,
+			date:     time.Date(2023, time.September, 29, 0, 0, 0, 0, time.UTC),
+			wantRate: "4.8",
+		},
+		"json rate on the record date": {
+			file:     "rates.json",
+			content:  jsonRates,
+			date:     time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC),
+			wantRate: "5.003",
+		},
+		"csv nearest rate after the date": {
+			file:     "rates.csv",
+			content:  csvRates,
+			date:     time.Date(2023, time.September, 10, 0, 0, 0, 0, time.UTC),
+			policy:   gateway.PolicyNearest,
+			wantRate: "5.003",
+		},
+		"csv rate at the start of the quarter": {
+			file:     "rates.csv",
+			content:  csvRates,
+			date:     time.Date(2023, time.November, 15, 0, 0, 0, 0, time.UTC),
+			policy:   gateway.PolicyQuarterStart,
+			wantRate: "5.003",
+		},
+		"json rate at the start of the quarter": {
+			file:     "rates.json",
+			content:  jsonRates,
+			date:     time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC),
+			policy:   gateway.PolicyQuarterStart,
+			wantRate: "4.8",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			p, err := NewFileProvider(writeFile(t, tc.file, tc.content))
+			assert.NoError(t, err)
+
+			got, gotErr := p.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{
+				TransactionDate: tc.date,
+				Country:         "Brazil",
+				Currency:        "Real",
+				Policy:          tc.policy,
+			})
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantRate, got.ExchangeRate)
+		})
+	}
+}
+
+func TestFileProvider_GetExchangeRate_NoRate(t *testing.T) {
+	p, err := NewFileProvider(writeFile(t, "rates.csv", csvRates))
+	assert.NoError(t, err)
+
+	testCases := map[string]gateway.CurrencyExchangeRateRequest{
+		"unknown currency": {
+			TransactionDate: time.Date(2023, time.October, 15, 0, 0, 0, 0, time.UTC),
+			Country:         "Mexico",
+			Currency:        "Peso",
+		},
+		"rate older than six months": {
+			TransactionDate: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+			Country:         "Brazil",
+			Currency:        "Real",
+		},
+		"rate after the date": {
+			TransactionDate: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Country:         "Brazil",
+			Currency:        "Real",
+		},
+	}
+
+	for title, input := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := p.GetExchangeRate(context.Background(), input)
+			assert.ErrorIs(t, gotErr, httpresponse.ErrNoCurrencyConversion)
+			assert.Nil(t, got)
+		})
+	}
+}
+
+func TestNewFileProvider_Error(t *testing.T) {
+	testCases := map[string]struct {
+		file    string
+		content string
+	}{
+		"unsupported format": {file: "rates.txt", content: csvRates},
+		"invalid header":     {file: "rates.csv", content: "currency,rate,date\n"},
+		"invalid rate":       {file: "rates.csv", content: "country_currency_desc,exchange_rate,record_date\nBrazil-Real,abc,2023-06-30\n"},
+		"invalid date":       {file: "rates.csv", content: "country_currency_desc,exchange_rate,record_date\nBrazil-Real,4.8,30/06/2023\n"},
+		"invalid json":       {file: "rates.json", content: `{"country_currency_desc": "Brazil-Real"}`},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := NewFileProvider(writeFile(t, tc.file, tc.content))
+			assert.Error(t, gotErr)
+			assert.Nil(t, got)
+		})
+	}
+}
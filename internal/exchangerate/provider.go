@@ -0,0 +1,129 @@
+package exchangerate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"golang.org/x/exp/slog"
+)
+
+// Names of the built-in providers.
+const (
+	ProviderStatic   = "static"
+	ProviderLocal    = "local"
+	ProviderTreasury = "treasury"
+	ProviderFile     = "file"
+)
+
+// Provider supplies exchange rates from a single source.
+// It returns httpresponse.ErrNoCurrencyConversion when the source has no rate for the request.
+type Provider interface {
+	Name() string
+	GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+}
+
+type source interface {
+	GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+}
+
+// namedProvider gives a name to an exchange rate source.
+type namedProvider struct {
+	name string
+	source
+}
+
+// NewProvider creates a provider with the given name backed by the source.
+func NewProvider(name string, src source) Provider {
+	return &namedProvider{
+		name:   name,
+		source: src,
+	}
+}
+
+// Name returns the name of the provider.
+func (p *namedProvider) Name() string {
+	return p.name
+}
+
+// Registry holds the available providers by name.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry creates a registry with the given providers.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{
+		providers: make(map[string]Provider, len(providers)),
+	}
+
+	for _, p := range providers {
+		r.Register(p)
+	}
+
+	return r
+}
+
+// Register adds the provider to the registry, replacing any provider with the same name.
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Chain creates a chain trying the registered providers in the given order.
+func (r *Registry) Chain(order ...string) (*Chain, error) {
+	if len(order) == 0 {
+		return nil, errors.New("at least one exchange rate provider is required")
+	}
+
+	providers := make([]Provider, 0, len(order))
+	for _, name := range order {
+		p, ok := r.providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown exchange rate provider %q", name)
+		}
+		providers = append(providers, p)
+	}
+
+	return NewChain(providers...), nil
+}
+
+// Chain tries a list of providers in order and returns the first exchange rate found.
+type Chain struct {
+	providers []Provider
+}
+
+// NewChain creates a chain trying the providers in the given order.
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{
+		providers: providers,
+	}
+}
+
+// GetExchangeRate returns the exchange rate of the first provider that has one, recording the provider name in it.
+// A provider failing for any reason other than having no rate is skipped; its error is returned only if no other
+// provider has a rate, since the failed provider might have had one.
+func (c *Chain) GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	var lastErr error
+
+	for _, p := range c.providers {
+		rate, err := p.GetExchangeRate(ctx, input)
+		if err == nil {
+			res := *rate
+			res.Provider = p.Name()
+			return &res, nil
+		}
+
+		if !errors.Is(err, httpresponse.ErrNoCurrencyConversion) {
+			slog.WarnContext(ctx, "Exchange rate provider failed, trying the next one", slog.String("provider", p.Name()), slog.String("error", err.Error()))
+			lastErr = fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return nil, httpresponse.ErrNoCurrencyConversion
+}
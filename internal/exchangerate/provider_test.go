@@ -0,0 +1,141 @@
+package exchangerate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+type stubSource struct {
+	calls           int
+	getExchangeRate func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+}
+
+func (s *stubSource) GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	s.calls++
+	return s.getExchangeRate(input)
+}
+
+func rateSource(rate string) *stubSource {
+	return &stubSource{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			return &gateway.CurrencyExchangeRate{
+				CountryCurrencyDesc: input.CountryCurrencyDesc(),
+				ExchangeRate:        rate,
+				RecordDate:          "2023-09-30",
+			}, nil
+		},
+	}
+}
+
+func errSource(err error) *stubSource {
+	return &stubSource{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			return nil, err
+		},
+	}
+}
+
+func TestChain_GetExchangeRate(t *testing.T) {
+	input := gateway.CurrencyExchangeRateRequest{Country: "Brazil", Currency: "Real"}
+
+	testCases := map[string]struct {
+		sources      []*stubSource
+		wantRate     string
+		wantProvider string
+		wantCalls    []int
+	}{
+		"first provider has the rate": {
+			sources:      []*stubSource{rateSource("5.0"), rateSource("4.9")},
+			wantRate:     "5.0",
+			wantProvider: "first",
+			wantCalls:    []int{1, 0},
+		},
+		"first provider has no rate": {
+			sources:      []*stubSource{errSource(httpresponse.ErrNoCurrencyConversion), rateSource("4.9")},
+			wantRate:     "4.9",
+			wantProvider: "second",
+			wantCalls:    []int{1, 1},
+		},
+		"first provider fails": {
+			sources:      []*stubSource{errSource(errors.New("connection refused")), rateSource("4.9")},
+			wantRate:     "4.9",
+			wantProvider: "second",
+			wantCalls:    []int{1, 1},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			chain := NewChain(NewProvider("first", tc.sources[0]), NewProvider("second", tc.sources[1]))
+
+			got, gotErr := chain.GetExchangeRate(context.Background(), input)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantRate, got.ExchangeRate)
+			assert.Equal(t, tc.wantProvider, got.Provider)
+			assert.Equal(t, tc.wantCalls, []int{tc.sources[0].calls, tc.sources[1].calls})
+		})
+	}
+}
+
+func TestChain_GetExchangeRate_Error(t *testing.T) {
+	input := gateway.CurrencyExchangeRateRequest{Country: "Brazil", Currency: "Real"}
+	errDB := errors.New("database is locked")
+
+	testCases := map[string]struct {
+		sources []*stubSource
+		wantErr error
+	}{
+		"no provider has the rate": {
+			sources: []*stubSource{errSource(httpresponse.ErrNoCurrencyConversion), errSource(httpresponse.ErrNoCurrencyConversion)},
+			wantErr: httpresponse.ErrNoCurrencyConversion,
+		},
+		"provider failed and others have no rate": {
+			sources: []*stubSource{errSource(errDB), errSource(httpresponse.ErrNoCurrencyConversion)},
+			wantErr: errDB,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			chain := NewChain(NewProvider("first", tc.sources[0]), NewProvider("second", tc.sources[1]))
+
+			got, gotErr := chain.GetExchangeRate(context.Background(), input)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Nil(t, got)
+		})
+	}
+}
+
+func TestRegistry_Chain(t *testing.T) {
+	registry := NewRegistry(NewProvider("first", rateSource("5.0")), NewProvider("second", rateSource("4.9")))
+
+	chain, err := registry.Chain("second", "first")
+	assert.NoError(t, err)
+
+	got, gotErr := chain.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{Country: "Brazil", Currency: "Real"})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "second", got.Provider)
+	assert.Equal(t, "4.9", got.ExchangeRate)
+}
+
+func TestRegistry_Chain_Error(t *testing.T) {
+	registry := NewRegistry(NewProvider("first", rateSource("5.0")))
+
+	testCases := map[string][]string{
+		"no provider":      nil,
+		"unknown provider": {"first", "other"},
+	}
+
+	for title, order := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := registry.Chain(order...)
+			assert.Error(t, gotErr)
+			assert.Nil(t, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package exchangerate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+// StaticProvider supplies fixed exchange rates that override any other source, whatever the transaction date.
+type StaticProvider struct {
+	rates map[string]string
+}
+
+// NewStaticProvider creates a provider from a table of exchange rates by country currency description,
+// e.g. {"Canada-Dollar": "1.35"}. Descriptions are matched case-insensitively.
+func NewStaticProvider(rates map[string]string) (*StaticProvider, error) {
+	p := &StaticProvider{
+		rates: make(map[string]string, len(rates)),
+	}
+
+	for desc, rate := range rates {
+		if _, err := money.ParseRate(rate); err != nil {
+			return nil, fmt.Errorf("override for %s: %w", desc, err)
+		}
+		p.rates[strings.ToLower(desc)] = rate
+	}
+
+	return p, nil
+}
+
+// Name returns the name of the provider.
+func (p *StaticProvider) Name() string {
+	return ProviderStatic
+}
+
+// GetExchangeRate returns the override for the country currency, if any.
+func (p *StaticProvider) GetExchangeRate(_ context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	desc := input.CountryCurrencyDesc()
+
+	rate, ok := p.rates[strings.ToLower(desc)]
+	if !ok {
+		return nil, httpresponse.ErrNoCurrencyConversion
+	}
+
+	return &gateway.CurrencyExchangeRate{
+		CountryCurrencyDesc: desc,
+		ExchangeRate:        rate,
+	}, nil
+}
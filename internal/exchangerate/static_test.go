@@ -0,0 +1,34 @@
+package exchangerate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestStaticProvider_GetExchangeRate(t *testing.T) {
+	p, err := NewStaticProvider(map[string]string{"Canada-Dollar": "1.35"})
+	assert.NoError(t, err)
+
+	got, gotErr := p.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{Country: "Canada", Currency: "Dollar"})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.35"}, got)
+
+	got, gotErr = p.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{Country: "canada", Currency: "dollar"})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "1.35", got.ExchangeRate)
+
+	got, gotErr = p.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{Country: "Brazil", Currency: "Real"})
+	assert.ErrorIs(t, gotErr, httpresponse.ErrNoCurrencyConversion)
+	assert.Nil(t, got)
+}
+
+func TestNewStaticProvider_Error(t *testing.T) {
+	got, gotErr := NewStaticProvider(map[string]string{"Canada-Dollar": "-1"})
+	assert.ErrorIs(t, gotErr, money.ErrInvalidRate)
+	assert.Nil(t, got)
+}
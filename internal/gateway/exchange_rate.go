@@ -1,38 +1,99 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	baseURL    = "https://api.fiscaldata.treasury.gov/services/api/fiscal_service/"
+	// DefaultBaseURL is the base URL of the Treasury Fiscal Data API.
+	DefaultBaseURL = "https://api.fiscaldata.treasury.gov/services/api/fiscal_service/"
+
+	// DefaultLookbackMonths is how many months before the transaction date an exchange rate may be used by default.
+	DefaultLookbackMonths = 6
+
+	// MaxLookbackMonths is the largest number of months an exchange rate may be looked for.
+	MaxLookbackMonths = 120
+
 	endpoint   = "v1/accounting/od/rates_of_exchange"
 	fields     = "?fields=country_currency_desc,exchange_rate,record_date"
 	sort       = "&sort=-record_date"
+	pageSort   = "&sort=record_date,country_currency_desc"
 	dateFormat = "2006-01-02"
+
+	// DefaultPageSize is the number of records requested per page when paging through the dataset.
+	DefaultPageSize = 1000
 )
 
+// tracerName is the name of the tracer of the gateway methods.
+const tracerName = "github.com/vickiliou/challenge-wex/internal/gateway"
+
 // CurrencyExchangeRateRequest represents the request structure for exchange rate.
 type CurrencyExchangeRateRequest struct {
 	TransactionDate time.Time
 	Country         string
 	Currency        string
+
+	// LookbackMonths is how many months before the transaction date a rate may be used, DefaultLookbackMonths if zero.
+	LookbackMonths int
+
+	// Policy selects the rate applying to the transaction, DefaultPolicy if empty.
+	Policy RatePolicy
+}
+
+// CountryCurrencyDesc returns the Treasury country currency description, e.g. "Canada-Dollar".
+func (r CurrencyExchangeRateRequest) CountryCurrencyDesc() string {
+	return fmt.Sprintf("%s-%s", r.Country, r.Currency)
+}
+
+// Window returns the range of record dates in which an exchange rate may be used for the transaction,
+// by default from the lookback months before up to the transaction date.
+func (r CurrencyExchangeRateRequest) Window() (time.Time, time.Time) {
+	return r.Policy.Window(r.TransactionDate, r.LookbackMonths)
+}
+
+// Select picks the exchange rate applying to the transaction among rates within its window, or nil if there is none.
+func (r CurrencyExchangeRateRequest) Select(rates []CurrencyExchangeRate) *CurrencyExchangeRate {
+	return r.Policy.Select(r.TransactionDate, rates)
 }
 
 // CurrencyExchangeRate represents currency exchange rate data.
 type CurrencyExchangeRate struct {
 	CountryCurrencyDesc string `json:"country_currency_desc"`
 	ExchangeRate        string `json:"exchange_rate"`
+	RecordDate          string `json:"record_date"`
+
+	// Provider is the name of the exchange rate provider that supplied the rate.
+	Provider string `json:"-"`
 }
 
 // CurrencyExchangeRateResponse represents the response structure for exchange rate.
 type CurrencyExchangeRateResponse struct {
 	Data []CurrencyExchangeRate `json:"data"`
+	Meta ResponseMeta           `json:"meta"`
+}
+
+// ResponseMeta represents the paging metadata of a response.
+type ResponseMeta struct {
+	TotalCount int `json:"total-count"`
+	TotalPages int `json:"total-pages"`
+}
+
+// ExchangeRatePageRequest represents the request structure for a page of the exchange rate dataset.
+type ExchangeRatePageRequest struct {
+	Since      time.Time
+	PageNumber int
+	PageSize   int
 }
 
 type httpClient interface {
@@ -41,30 +102,85 @@ type httpClient interface {
 
 // Gateway is responsible for fetching exchange rate data.
 type Gateway struct {
-	httpClient httpClient
+	httpClient  httpClient
+	baseURL     string
+	callTimeout time.Duration
 }
 
-// NewGateway creates and returns a new instance of the Gateway.
-func NewGateway(httpClient httpClient) *Gateway {
+// NewGateway creates and returns a new instance of the Gateway calling the API at baseURL, e.g. DefaultBaseURL.
+// Each call is given at most callTimeout to complete, on top of the deadline of its context; zero means no limit.
+func NewGateway(httpClient httpClient, baseURL string, callTimeout time.Duration) *Gateway {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
 	return &Gateway{
-		httpClient: httpClient,
+		httpClient:  httpClient,
+		baseURL:     baseURL,
+		callTimeout: callTimeout,
 	}
 }
 
-// GetExchangeRate fetches the exchange rate for a specific date and returns the closest available rate.
-func (g *Gateway) GetExchangeRate(input CurrencyExchangeRateRequest) (*CurrencyExchangeRate, error) {
-	url := constructExchangeRateURL(input)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// GetExchangeRate fetches the exchange rates within the window of the transaction and returns
+// the one selected by the rate policy of the request.
+func (g *Gateway) GetExchangeRate(ctx context.Context, input CurrencyExchangeRateRequest) (_ *CurrencyExchangeRate, err error) {
+	ctx, span := startSpan(ctx, "GetExchangeRate")
+	defer func() { tracing.EndSpan(span, err, httpresponse.ErrNoCurrencyConversion) }()
+
+	resp, err := g.fetch(ctx, constructExchangeRateURL(g.baseURL, input))
+	if err != nil {
+		return nil, err
+	}
+
+	rate := input.Select(resp.Data)
+	if rate == nil {
+		return nil, httpresponse.ErrNoCurrencyConversion
+	}
+
+	return rate, nil
+}
+
+// GetExchangeRatePage fetches one page of the exchange rate dataset, ordered by record date,
+// including only the records published on or after the requested date.
+func (g *Gateway) GetExchangeRatePage(ctx context.Context, input ExchangeRatePageRequest) (_ *CurrencyExchangeRateResponse, err error) {
+	ctx, span := startSpan(ctx, "GetExchangeRatePage")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return g.fetch(ctx, constructExchangeRatePageURL(g.baseURL, input))
+}
+
+// startSpan starts the span of a method of the gateway.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "Gateway."+method)
+}
+
+// fetch requests the given URL and decodes the exchange rates response.
+// The request is cancelled when the context is done or the call timeout elapses, whichever comes first.
+func (g *Gateway) fetch(ctx context.Context, url string) (*CurrencyExchangeRateResponse, error) {
+	if g.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.callTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
 
 	res, err := g.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+		if errors.Is(err, context.Canceled) || errors.Is(err, httpresponse.ErrServiceUnavailable) {
+			return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+		}
+		return nil, fmt.Errorf("%w: failed to fetch exchange rates: %w", httpresponse.ErrServiceUnavailable, err)
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: API request failed with status code: %d", httpresponse.ErrServiceUnavailable, res.StatusCode)
+	}
+
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API request failed with status code: %d", res.StatusCode)
 	}
@@ -74,23 +190,44 @@ func (g *Gateway) GetExchangeRate(input CurrencyExchangeRateRequest) (*CurrencyE
 		return nil, fmt.Errorf("failed to decode exchange rates response: %w", err)
 	}
 
-	if len(resp.Data) == 0 {
-		return nil, httpresponse.ErrNoCurrencyConversion
-	}
-
-	return &resp.Data[0], nil
+	return &resp, nil
 }
 
 // constructExchangeRateURL constructs the URL for fetching exchange rates based on
-// the target country, target currency, and transaction date.
-func constructExchangeRateURL(input CurrencyExchangeRateRequest) string {
-	sixMonthsAgo := input.TransactionDate.AddDate(0, -6, 0).Format(dateFormat)
-	fmtTxnDate := input.TransactionDate.Format(dateFormat)
+// the target country, target currency, and the window of the transaction date.
+func constructExchangeRateURL(baseURL string, input CurrencyExchangeRateRequest) string {
+	from, to := input.Window()
+
+	filterParam := fmt.Sprintf("&filter=country_currency_desc:eq:%s,record_date:lte:%s,record_date:gte:%s",
+		input.CountryCurrencyDesc(), to.Format(dateFormat), from.Format(dateFormat))
+
+	pageParam := fmt.Sprintf("&page[size]=%d", DefaultPageSize)
+
+	url := baseURL + endpoint + fields + filterParam + sort + pageParam
+
+	return url
+}
+
+// constructExchangeRatePageURL constructs the URL for fetching a page of the exchange rate dataset.
+func constructExchangeRatePageURL(baseURL string, input ExchangeRatePageRequest) string {
+	pageSize := input.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	pageNumber := input.PageNumber
+	if pageNumber <= 0 {
+		pageNumber = 1
+	}
+
+	var filterParam string
+	if !input.Since.IsZero() {
+		filterParam = fmt.Sprintf("&filter=record_date:gte:%s", input.Since.Format(dateFormat))
+	}
 
-	filterParam := fmt.Sprintf("&filter=country_currency_desc:eq:%s-%s,record_date:lte:%s,record_date:gte:%s",
-		input.Country, input.Currency, fmtTxnDate, sixMonthsAgo)
+	pageParam := fmt.Sprintf("&page[number]=%d&page[size]=%d", pageNumber, pageSize)
 
-	url := baseURL + endpoint + fields + filterParam + sort
+	url := baseURL + endpoint + fields + filterParam + pageSort + pageParam
 
 	return url
 }
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -9,7 +10,13 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type mockHttpClient struct {
@@ -22,8 +29,9 @@ func (m *mockHttpClient) Do(req *http.Request) (*http.Response, error) {
 
 func TestGetExchangeRate(t *testing.T) {
 	testCases := map[string]struct {
-		body io.ReadCloser
-		want *CurrencyExchangeRate
+		policy RatePolicy
+		body   io.ReadCloser
+		want   *CurrencyExchangeRate
 	}{
 		"return only one exchange rate": {
 			body: io.NopCloser(bytes.NewBufferString(`{
@@ -39,6 +47,28 @@ func TestGetExchangeRate(t *testing.T) {
 				ExchangeRate:        "1.234",
 			},
 		},
+		"nearest policy, should return the closest one": {
+			policy: PolicyNearest,
+			body: io.NopCloser(bytes.NewBufferString(`{
+				"data": [
+					{
+						"country_currency_desc": "Canada-Dollar",
+						"exchange_rate": "1.345",
+						"record_date": "2023-09-30"
+					},
+					{
+						"country_currency_desc": "Canada-Dollar",
+						"exchange_rate": "1.322",
+						"record_date": "2023-06-30"
+					}
+				]
+			}`)),
+			want: &CurrencyExchangeRate{
+				CountryCurrencyDesc: "Canada-Dollar",
+				ExchangeRate:        "1.345",
+				RecordDate:          "2023-09-30",
+			},
+		},
 		"return more than one exchange rate, should return the first one": {
 			body: io.NopCloser(bytes.NewBufferString(`{
 				"data": [
@@ -70,14 +100,15 @@ func TestGetExchangeRate(t *testing.T) {
 				},
 			}
 
-			gw := NewGateway(mockClient)
+			gw := NewGateway(mockClient, DefaultBaseURL, 0)
 			input := CurrencyExchangeRateRequest{
 				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
 				Country:         "Canada",
 				Currency:        "Dollar",
+				Policy:          tc.policy,
 			}
 
-			got, gotErr := gw.GetExchangeRate(input)
+			got, gotErr := gw.GetExchangeRate(context.Background(), input)
 			assert.NoError(t, gotErr)
 			assert.Equal(t, tc.want, got)
 		})
@@ -101,6 +132,14 @@ func TestGetExchangeRate_Error(t *testing.T) {
 			},
 			wantErr: someError.Error(),
 		},
+		"API unreachable": {
+			mockClient: &mockHttpClient{
+				do: func(req *http.Request) (*http.Response, error) {
+					return nil, someError
+				},
+			},
+			wantErr: httpresponse.ErrServiceUnavailable.Error(),
+		},
 		"API request failed": {
 			mockClient: &mockHttpClient{
 				do: func(req *http.Request) (*http.Response, error) {
@@ -112,6 +151,17 @@ func TestGetExchangeRate_Error(t *testing.T) {
 			},
 			wantStatusCode: http.StatusBadRequest,
 		},
+		"API unavailable": {
+			mockClient: &mockHttpClient{
+				do: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						Body:       http.NoBody,
+						StatusCode: http.StatusServiceUnavailable,
+					}, nil
+				},
+			},
+			wantErr: httpresponse.ErrServiceUnavailable.Error(),
+		},
 		"failed to decode API response": {
 			mockClient: &mockHttpClient{
 				do: func(req *http.Request) (*http.Response, error) {
@@ -138,7 +188,7 @@ func TestGetExchangeRate_Error(t *testing.T) {
 
 	for title, tc := range testCases {
 		t.Run(title, func(t *testing.T) {
-			gw := NewGateway(tc.mockClient)
+			gw := NewGateway(tc.mockClient, DefaultBaseURL, 0)
 
 			input := CurrencyExchangeRateRequest{
 				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
@@ -146,9 +196,237 @@ func TestGetExchangeRate_Error(t *testing.T) {
 				Currency:        "Dollar",
 			}
 
-			got, gotErr := gw.GetExchangeRate(input)
+			got, gotErr := gw.GetExchangeRate(context.Background(), input)
 			assert.Nil(t, got)
 			assert.ErrorContains(t, gotErr, tc.wantErr)
 		})
 	}
 }
+
+func TestGetExchangeRatePage(t *testing.T) {
+	var gotURL string
+	mockClient := &mockHttpClient{
+		do: func(req *http.Request) (*http.Response, error) {
+			gotURL = req.URL.String()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(bytes.NewBufferString(`{
+					"data": [
+						{
+							"country_currency_desc": "Canada-Dollar",
+							"exchange_rate": "1.353",
+							"record_date": "2023-09-30"
+						}
+					],
+					"meta": {
+						"total-count": 1,
+						"total-pages": 1
+					}
+				}`)),
+			}, nil
+		},
+	}
+
+	gw := NewGateway(mockClient, DefaultBaseURL, 0)
+	input := ExchangeRatePageRequest{
+		Since:      time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC),
+		PageNumber: 2,
+		PageSize:   100,
+	}
+
+	got, gotErr := gw.GetExchangeRatePage(context.Background(), input)
+	assert.NoError(t, gotErr)
+
+	want := &CurrencyExchangeRateResponse{
+		Data: []CurrencyExchangeRate{
+			{
+				CountryCurrencyDesc: "Canada-Dollar",
+				ExchangeRate:        "1.353",
+				RecordDate:          "2023-09-30",
+			},
+		},
+		Meta: ResponseMeta{
+			TotalCount: 1,
+			TotalPages: 1,
+		},
+	}
+
+	assert.Equal(t, want, got)
+	assert.Contains(t, gotURL, "filter=record_date:gte:2023-06-30")
+	assert.Contains(t, gotURL, "page[number]=2&page[size]=100")
+}
+
+func TestConstructExchangeRatePageURL(t *testing.T) {
+	testCases := map[string]struct {
+		input ExchangeRatePageRequest
+		want  string
+	}{
+		"full dataset with default paging": {
+			input: ExchangeRatePageRequest{},
+			want:  DefaultBaseURL + endpoint + fields + pageSort + "&page[number]=1&page[size]=1000",
+		},
+		"records since a date": {
+			input: ExchangeRatePageRequest{
+				Since:      time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC),
+				PageNumber: 3,
+				PageSize:   50,
+			},
+			want: DefaultBaseURL + endpoint + fields + "&filter=record_date:gte:2023-03-31" + pageSort + "&page[number]=3&page[size]=50",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got := constructExchangeRatePageURL(DefaultBaseURL, tc.input)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConstructExchangeRateURL(t *testing.T) {
+	testCases := map[string]struct {
+		policy RatePolicy
+		want   string
+	}{
+		"on or before": {
+			policy: PolicyOnOrBefore,
+			want:   "&filter=country_currency_desc:eq:Canada-Dollar,record_date:lte:2023-09-21,record_date:gte:2023-03-21",
+		},
+		"nearest": {
+			policy: PolicyNearest,
+			want:   "&filter=country_currency_desc:eq:Canada-Dollar,record_date:lte:2024-03-21,record_date:gte:2023-03-21",
+		},
+		"quarter start": {
+			policy: PolicyQuarterStart,
+			want:   "&filter=country_currency_desc:eq:Canada-Dollar,record_date:lte:2023-07-01,record_date:gte:2023-01-01",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got := constructExchangeRateURL(DefaultBaseURL, CurrencyExchangeRateRequest{
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+				Policy:          tc.policy,
+			})
+			assert.Equal(t, DefaultBaseURL+endpoint+fields+tc.want+sort+"&page[size]=1000", got)
+		})
+	}
+}
+
+func TestCurrencyExchangeRateRequest_Window(t *testing.T) {
+	input := CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.September, 21, 15, 30, 0, 0, time.FixedZone("BRT", -3*60*60)),
+		Country:         "Canada",
+		Currency:        "Dollar",
+	}
+
+	gotFrom, gotTo := input.Window()
+	assert.Equal(t, time.Date(2023, time.March, 21, 0, 0, 0, 0, time.UTC), gotFrom)
+	assert.Equal(t, time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC), gotTo)
+	assert.Equal(t, "Canada-Dollar", input.CountryCurrencyDesc())
+}
+
+func TestCurrencyExchangeRateRequest_Window_Lookback(t *testing.T) {
+	input := CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		LookbackMonths:  12,
+	}
+
+	gotFrom, gotTo := input.Window()
+	assert.Equal(t, time.Date(2022, time.September, 21, 0, 0, 0, 0, time.UTC), gotFrom)
+	assert.Equal(t, time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC), gotTo)
+}
+
+func TestGetExchangeRate_Context(t *testing.T) {
+	var gotDeadline time.Time
+	mockClient := &mockHttpClient{
+		do: func(req *http.Request) (*http.Response, error) {
+			gotDeadline, _ = req.Context().Deadline()
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"data":[{"country_currency_desc":"Canada-Dollar","exchange_rate":"1.234"}]}`)),
+			}, nil
+		},
+	}
+
+	gw := NewGateway(mockClient, DefaultBaseURL, time.Second)
+	input := CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Country:         "Canada",
+		Currency:        "Dollar",
+	}
+
+	start := time.Now()
+	_, gotErr := gw.GetExchangeRate(context.Background(), input)
+	assert.NoError(t, gotErr)
+	assert.WithinDuration(t, start.Add(time.Second), gotDeadline, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, gotErr := gw.GetExchangeRate(ctx, input)
+	assert.ErrorIs(t, gotErr, context.Canceled)
+	assert.Nil(t, got)
+}
+
+func TestGetExchangeRate_Span(t *testing.T) {
+	testCases := map[string]struct {
+		body       string
+		statusCode int
+		wantStatus codes.Code
+	}{
+		"rate found": {
+			body:       `{"data":[{"country_currency_desc":"Canada-Dollar","exchange_rate":"1.234"}]}`,
+			statusCode: http.StatusOK,
+			wantStatus: codes.Unset,
+		},
+		"no rate": {
+			body:       `{"data":[]}`,
+			statusCode: http.StatusOK,
+			wantStatus: codes.Unset,
+		},
+		"API unavailable": {
+			statusCode: http.StatusServiceUnavailable,
+			wantStatus: codes.Error,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			prev := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+			var gotSpan trace.SpanContext
+			mockClient := &mockHttpClient{
+				do: func(req *http.Request) (*http.Response, error) {
+					gotSpan = trace.SpanContextFromContext(req.Context())
+					return &http.Response{
+						StatusCode: tc.statusCode,
+						Body:       io.NopCloser(bytes.NewBufferString(tc.body)),
+					}, nil
+				},
+			}
+
+			gw := NewGateway(mockClient, DefaultBaseURL, 0)
+			input := CurrencyExchangeRateRequest{
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+			}
+			_, _ = gw.GetExchangeRate(context.Background(), input)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "Gateway.GetExchangeRate", spans[0].Name())
+			assert.Equal(t, spans[0].SpanContext().SpanID(), gotSpan.SpanID())
+			assert.Equal(t, tc.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
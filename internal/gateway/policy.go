@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RatePolicy is the rule selecting which exchange rate applies to a transaction.
+type RatePolicy string
+
+// Rate selection policies.
+const (
+	// PolicyOnOrBefore selects the most recent rate on or before the transaction date, within the lookback months.
+	PolicyOnOrBefore RatePolicy = "on_or_before"
+
+	// PolicyNearest selects the rate closest to the transaction date, within the lookback months before or after it.
+	// On a tie, the rate before the transaction date is selected.
+	PolicyNearest RatePolicy = "nearest"
+
+	// PolicyQuarterStart selects the rate in effect on the first day of the transaction's quarter,
+	// i.e. the most recent rate on or before that day, within the lookback months.
+	PolicyQuarterStart RatePolicy = "quarter_start"
+
+	// DefaultPolicy is the policy used when none is requested.
+	DefaultPolicy = PolicyOnOrBefore
+)
+
+// Policies lists the supported rate selection policies.
+var Policies = []RatePolicy{PolicyOnOrBefore, PolicyNearest, PolicyQuarterStart}
+
+// ParsePolicy returns the policy with the given name, case-insensitively.
+func ParsePolicy(s string) (RatePolicy, error) {
+	for _, p := range Policies {
+		if strings.EqualFold(strings.TrimSpace(s), string(p)) {
+			return p, nil
+		}
+	}
+
+	names := make([]string, len(Policies))
+	for i, p := range Policies {
+		names[i] = string(p)
+	}
+
+	return "", fmt.Errorf("rate policy must be one of %s, got %q", strings.Join(names, ", "), s)
+}
+
+// Window returns the range of record dates in which the policy may select a rate for the transaction date.
+func (p RatePolicy) Window(transactionDate time.Time, lookbackMonths int) (time.Time, time.Time) {
+	if lookbackMonths <= 0 {
+		lookbackMonths = DefaultLookbackMonths
+	}
+
+	day := p.anchor(transactionDate)
+
+	if p == PolicyNearest {
+		return day.AddDate(0, -lookbackMonths, 0), day.AddDate(0, lookbackMonths, 0)
+	}
+
+	return day.AddDate(0, -lookbackMonths, 0), day
+}
+
+// Select picks the rate applying to the transaction date among rates within the window of the policy.
+// It returns nil if there is none.
+func (p RatePolicy) Select(transactionDate time.Time, rates []CurrencyExchangeRate) *CurrencyExchangeRate {
+	day := p.anchor(transactionDate).Format(dateFormat)
+
+	var best *CurrencyExchangeRate
+	for i := range rates {
+		rate := &rates[i]
+
+		switch {
+		case best == nil:
+			best = rate
+		case p == PolicyNearest:
+			if closer(day, rate.RecordDate, best.RecordDate) {
+				best = rate
+			}
+		case rate.RecordDate > best.RecordDate:
+			best = rate
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	res := *best
+	return &res
+}
+
+// anchor returns the day from which the policy looks for a rate.
+func (p RatePolicy) anchor(transactionDate time.Time) time.Time {
+	y, m, d := transactionDate.Date()
+
+	if p == PolicyQuarterStart {
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// closer reports whether the record date a is closer to day than the record date b,
+// preferring the earlier one on a tie.
+func closer(day, a, b string) bool {
+	da, db := distance(day, a), distance(day, b)
+	if da != db {
+		return da < db
+	}
+	return a < b
+}
+
+// distance returns the time between two dates in the "2006-01-02" format.
+func distance(a, b string) time.Duration {
+	ta, _ := time.Parse(dateFormat, a)
+	tb, _ := time.Parse(dateFormat, b)
+
+	d := ta.Sub(tb)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  RatePolicy
+	}{
+		"on or before":     {input: "on_or_before", want: PolicyOnOrBefore},
+		"nearest":          {input: "nearest", want: PolicyNearest},
+		"quarter start":    {input: " Quarter_Start ", want: PolicyQuarterStart},
+		"unknown":          {input: "latest"},
+		"empty is invalid": {input: ""},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := ParsePolicy(tc.input)
+			assert.Equal(t, tc.want, got)
+			if tc.want == "" {
+				assert.ErrorContains(t, gotErr, "rate policy must be one of on_or_before, nearest, quarter_start")
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestRatePolicy_Window(t *testing.T) {
+	transactionDate := time.Date(2023, time.August, 21, 15, 30, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		policy   RatePolicy
+		lookback int
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		"default policy": {
+			wantFrom: time.Date(2023, time.February, 21, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2023, time.August, 21, 0, 0, 0, 0, time.UTC),
+		},
+		"on or before with lookback": {
+			policy:   PolicyOnOrBefore,
+			lookback: 3,
+			wantFrom: time.Date(2023, time.May, 21, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2023, time.August, 21, 0, 0, 0, 0, time.UTC),
+		},
+		"nearest": {
+			policy:   PolicyNearest,
+			lookback: 3,
+			wantFrom: time.Date(2023, time.May, 21, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2023, time.November, 21, 0, 0, 0, 0, time.UTC),
+		},
+		"quarter start": {
+			policy:   PolicyQuarterStart,
+			lookback: 3,
+			wantFrom: time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotFrom, gotTo := tc.policy.Window(transactionDate, tc.lookback)
+			assert.Equal(t, tc.wantFrom, gotFrom)
+			assert.Equal(t, tc.wantTo, gotTo)
+		})
+	}
+}
+
+func TestRatePolicy_Select(t *testing.T) {
+	transactionDate := time.Date(2023, time.August, 21, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		policy RatePolicy
+		dates  []string
+		want   string
+	}{
+		"on or before": {
+			policy: PolicyOnOrBefore,
+			dates:  []string{"2023-03-31", "2023-06-30", "2023-05-15"},
+			want:   "2023-06-30",
+		},
+		"nearest after": {
+			policy: PolicyNearest,
+			dates:  []string{"2023-06-30", "2023-09-30", "2023-08-31"},
+			want:   "2023-08-31",
+		},
+		"nearest before": {
+			policy: PolicyNearest,
+			dates:  []string{"2023-09-30", "2023-08-15"},
+			want:   "2023-08-15",
+		},
+		"nearest tie prefers before": {
+			policy: PolicyNearest,
+			dates:  []string{"2023-08-31", "2023-08-11"},
+			want:   "2023-08-11",
+		},
+		"quarter start": {
+			policy: PolicyQuarterStart,
+			dates:  []string{"2023-03-31", "2023-06-30"},
+			want:   "2023-06-30",
+		},
+		"no rate": {
+			policy: PolicyNearest,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			var rates []CurrencyExchangeRate
+			for _, date := range tc.dates {
+				rates = append(rates, CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", RecordDate: date})
+			}
+
+			got := tc.policy.Select(transactionDate, rates)
+			if tc.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tc.want, got.RecordDate)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"golang.org/x/exp/slog"
+)
+
+type catalog interface {
+	List(ctx context.Context) (*currency.CatalogResponse, error)
+}
+
+// CurrencyHandler is responsible for handling HTTP requests related to the supported currencies.
+type CurrencyHandler struct {
+	catalog catalog
+}
+
+// NewCurrencyHandler creates a new currency handler with the given catalog.
+func NewCurrencyHandler(catalog catalog) *CurrencyHandler {
+	return &CurrencyHandler{
+		catalog: catalog,
+	}
+}
+
+// List lists the currencies transactions can be converted to.
+func (h *CurrencyHandler) List(w http.ResponseWriter, r *http.Request) {
+	res, err := h.catalog.List(r.Context())
+	if err != nil {
+		httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+		httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+		return
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Currencies listed successfully", "count", len(res.Data))
+}
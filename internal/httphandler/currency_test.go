@@ -0,0 +1,71 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/currency"
+)
+
+type stubCatalog struct {
+	list func(ctx context.Context) (*currency.CatalogResponse, error)
+}
+
+func (s *stubCatalog) List(ctx context.Context) (*currency.CatalogResponse, error) {
+	return s.list(ctx)
+}
+
+func TestCurrency_List(t *testing.T) {
+	want := currency.CatalogResponse{
+		Data: []currency.CatalogEntry{
+			{
+				CountryCurrencyDesc: "Canada-Dollar",
+				Country:             "Canada",
+				Currency:            "Dollar",
+				ISOCountry:          "CA",
+				ISOCurrency:         "CAD",
+				RatesFrom:           "2001-03-31",
+				RatesTo:             "2023-09-30",
+			},
+		},
+	}
+
+	mockCatalog := &stubCatalog{
+		list: func(ctx context.Context) (*currency.CatalogResponse, error) {
+			return &want, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/currencies", nil)
+	w := httptest.NewRecorder()
+
+	NewCurrencyHandler(mockCatalog).List(w, req)
+
+	var got currency.CatalogResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+}
+
+func TestCurrency_List_Error(t *testing.T) {
+	mockCatalog := &stubCatalog{
+		list: func(ctx context.Context) (*currency.CatalogResponse, error) {
+			return nil, errors.New("database is locked")
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/currencies", nil)
+	w := httptest.NewRecorder()
+
+	NewCurrencyHandler(mockCatalog).List(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,229 @@
+package httphandler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+	"golang.org/x/exp/slog"
+)
+
+// Formats of an export. exportExcel is CSV meant to be opened in spreadsheet applications such as Excel:
+// it starts with a UTF-8 byte order mark, ends lines with CRLF and keeps cells from being read as formulas.
+const (
+	exportCSV   = "csv"
+	exportExcel = "excel"
+	exportJSONL = "jsonl"
+)
+
+// utf8BOM is the byte order mark spreadsheet applications need to read a CSV file as UTF-8.
+const utf8BOM = "\ufeff"
+
+// exportFlushRows is the number of rows after which the exported rows are flushed to the client.
+const exportFlushRows = 500
+
+// Export streams the transactions matching the query filters, converted to the requested currency, as CSV, CSV for
+// spreadsheets or JSONL.
+// The format is given by the format query parameter, or else negotiated from the Accept header, CSV by default.
+// Once the first row is sent, a failure can no longer be reported, so the response is aborted instead.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format, err := exportFormat(r)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+		httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+		httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+		return
+	}
+
+	input := transaction.ExportRequest{
+		Filter: transaction.ConversionFilter{
+			From:        query.Get("from"),
+			To:          query.Get("to"),
+			MinAmount:   query.Get("min_amount"),
+			MaxAmount:   query.Get("max_amount"),
+			Description: query.Get("description"),
+		},
+		Country:        query.Get("country"),
+		Currency:       query.Get("currency"),
+		RatePolicy:     query.Get("rate_policy"),
+		LookbackMonths: query.Get("lookback_months"),
+	}
+
+	ew := &exportWriter{w: w, format: format}
+
+	err = h.svc.Export(r.Context(), input, ew.write)
+	if err == nil {
+		err = ew.close()
+	}
+
+	if err != nil {
+		if ew.started {
+			slog.ErrorContext(r.Context(), "Export aborted", "rows", ew.rows, "error", err.Error())
+			panic(http.ErrAbortHandler)
+		}
+
+		switch {
+		case errors.Is(err, httpresponse.ErrValidation):
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		default:
+			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	slog.InfoContext(r.Context(), "Transactions exported successfully", "count", ew.rows, "format", format)
+}
+
+// exportFormat returns the format of an export from the format query parameter or the Accept header.
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch strings.ToLower(format) {
+		case exportCSV:
+			return exportCSV, nil
+		case exportExcel:
+			return exportExcel, nil
+		case exportJSONL:
+			return exportJSONL, nil
+		default:
+			return "", fmt.Errorf("format must be %s, %s or %s", exportCSV, exportExcel, exportJSONL)
+		}
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case "text/csv":
+			return exportCSV, nil
+		case "application/vnd.ms-excel":
+			return exportExcel, nil
+		case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+			return exportJSONL, nil
+		}
+	}
+
+	return exportCSV, nil
+}
+
+// exportWriter writes the rows of an export to the response, sending the headers with the first row.
+type exportWriter struct {
+	w       http.ResponseWriter
+	format  string
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	rows    int
+}
+
+// start sends the response headers and, for CSV, the header row. The write deadline of the server is lifted
+// since an export may take longer to stream than any single response.
+func (e *exportWriter) start() error {
+	e.started = true
+
+	_ = http.NewResponseController(e.w).SetWriteDeadline(time.Time{})
+
+	contentType, extension := "text/csv; charset=utf-8", exportCSV
+	if e.format == exportJSONL {
+		contentType, extension = "application/x-ndjson", exportJSONL
+	}
+
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions.%s"`, extension))
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == exportJSONL {
+		e.json = json.NewEncoder(e.w)
+		return nil
+	}
+
+	e.csv = csv.NewWriter(e.w)
+
+	if e.format == exportExcel {
+		if _, err := io.WriteString(e.w, utf8BOM); err != nil {
+			return err
+		}
+		e.csv.UseCRLF = true
+	}
+
+	return e.csv.Write(transaction.ExportHeader)
+}
+
+// write writes a row, flushing the rows written so far every exportFlushRows rows.
+func (e *exportWriter) write(row transaction.ExportRow) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch e.format {
+	case exportJSONL:
+		err = e.json.Encode(row)
+	case exportExcel:
+		err = e.csv.Write(spreadsheetRecord(row.Record()))
+	default:
+		err = e.csv.Write(row.Record())
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// close completes the export, sending the headers if no row matched.
+func (e *exportWriter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	return e.flush()
+}
+
+// flush sends the rows written so far to the client.
+func (e *exportWriter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	if err := http.NewResponseController(e.w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// spreadsheetRecord escapes the fields of a CSV record that spreadsheet applications would evaluate as formulas,
+// e.g. a description starting with "=", by prefixing them with an apostrophe.
+func spreadsheetRecord(record []string) []string {
+	for i, field := range record {
+		if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+			record[i] = "'" + field
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,225 @@
+package httphandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+func TestTransaction_Export(t *testing.T) {
+	rate := money.MustParseRate("1.35")
+	converted := money.MustParse("13.50", "CAD")
+
+	rows := []transaction.ExportRow{
+		{
+			ID:                  "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			Description:         "food",
+			TransactionDate:     time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			OriginalAmount:      money.MustParse("10", money.USD),
+			ExchangeRate:        &rate,
+			RateDate:            "2023-06-30",
+			ConvertedAmount:     &converted,
+			CountryCurrencyDesc: "Canada-Dollar",
+			RateProvider:        "treasury",
+		},
+		{
+			ID:              "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+			Description:     "rent",
+			TransactionDate: time.Date(2023, time.September, 22, 0, 0, 0, 0, time.UTC),
+			OriginalAmount:  money.MustParse("1000", money.USD),
+			Error:           "no rate",
+		},
+	}
+
+	csvBody := "id,description,transaction_date,original_amount,exchange_rate,exchange_rate_date,converted_amount,country_currency_desc,exchange_rate_provider,error\n" +
+		"b62a64c9-0008-4148-99f6-9c8086a1dd42,food,2023-09-21T00:00:00Z,10.00,1.35,2023-06-30,13.50,Canada-Dollar,treasury,\n" +
+		"f3a1c2d4-0008-4148-99f6-9c8086a1dd42,rent,2023-09-22T00:00:00Z,1000.00,,,,,,no rate\n"
+
+	jsonlBody := `{"id":"b62a64c9-0008-4148-99f6-9c8086a1dd42","description":"food","transaction_date":"2023-09-21T00:00:00Z","original_amount":10.00,"exchange_rate":1.35,"exchange_rate_date":"2023-06-30","converted_amount":13.50,"country_currency_desc":"Canada-Dollar","exchange_rate_provider":"treasury"}` + "\n" +
+		`{"id":"f3a1c2d4-0008-4148-99f6-9c8086a1dd42","description":"rent","transaction_date":"2023-09-22T00:00:00Z","original_amount":1000.00,"error":"no rate"}` + "\n"
+
+	testCases := map[string]struct {
+		path            string
+		accept          string
+		rows            []transaction.ExportRow
+		wantContentType string
+		wantBody        string
+	}{
+		"csv by default": {
+			path:            "/transactions/export?currency=CAD",
+			rows:            rows,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        csvBody,
+		},
+		"jsonl from accept": {
+			path:            "/transactions/export?currency=CAD",
+			accept:          "text/html, application/x-ndjson;q=0.9",
+			rows:            rows,
+			wantContentType: "application/x-ndjson",
+			wantBody:        jsonlBody,
+		},
+		"format parameter over accept": {
+			path:            "/transactions/export?currency=CAD&format=JSONL",
+			accept:          "text/csv",
+			rows:            rows,
+			wantContentType: "application/x-ndjson",
+			wantBody:        jsonlBody,
+		},
+		"csv for spreadsheets": {
+			path: "/transactions/export?currency=CAD&format=excel",
+			rows: []transaction.ExportRow{{
+				ID:              "0a1b2c3d-0008-4148-99f6-9c8086a1dd42",
+				Description:     "=HYPERLINK(\"http://example.com\")",
+				TransactionDate: time.Date(2023, time.September, 20, 0, 0, 0, 0, time.UTC),
+				OriginalAmount:  money.MustParse("5", money.USD),
+				Error:           "no rate",
+			}},
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody: "\ufeffid,description,transaction_date,original_amount,exchange_rate,exchange_rate_date,converted_amount,country_currency_desc,exchange_rate_provider,error\r\n" +
+				"0a1b2c3d-0008-4148-99f6-9c8086a1dd42,\"'=HYPERLINK(\"\"http://example.com\"\")\",2023-09-20T00:00:00Z,5.00,,,,,,no rate\r\n",
+		},
+		"csv for spreadsheets from accept": {
+			path:            "/transactions/export?currency=CAD",
+			accept:          "application/vnd.ms-excel",
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "\ufeffid,description,transaction_date,original_amount,exchange_rate,exchange_rate_date,converted_amount,country_currency_desc,exchange_rate_provider,error\r\n",
+		},
+		"no rows": {
+			path:            "/transactions/export?currency=CAD",
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "id,description,transaction_date,original_amount,exchange_rate,exchange_rate_date,converted_amount,country_currency_desc,exchange_rate_provider,error\n",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{
+				export: func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+					for _, row := range tc.rows {
+						if err := write(row); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			h.Export(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantContentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+			assert.NotContains(t, w.Header().Get("Content-Disposition"), "excel")
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestTransaction_Export_Query(t *testing.T) {
+	mockSvc := &stubService{
+		export: func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+			return nil
+		},
+	}
+
+	path := "/transactions/export?country=Canada&currency=Dollar&from=2023-09-01&to=2023-09-30&min_amount=1&max_amount=99&description=food&rate_policy=nearest&lookback_months=3"
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	h.Export(w, req)
+
+	want := transaction.ExportRequest{
+		Filter: transaction.ConversionFilter{
+			From:        "2023-09-01",
+			To:          "2023-09-30",
+			MinAmount:   "1",
+			MaxAmount:   "99",
+			Description: "food",
+		},
+		Country:        "Canada",
+		Currency:       "Dollar",
+		RatePolicy:     "nearest",
+		LookbackMonths: "3",
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, mockSvc.receivedExportRequest)
+}
+
+func TestTransaction_Export_Error(t *testing.T) {
+	testCases := map[string]struct {
+		path           string
+		err            error
+		wantStatusCode int
+	}{
+		"invalid format": {
+			path:           "/transactions/export?currency=CAD&format=xml",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"validation error": {
+			path:           "/transactions/export",
+			err:            httpresponse.ErrValidation,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"unexpected error": {
+			path:           "/transactions/export?currency=CAD",
+			err:            errors.New("some error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{
+				export: func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+					return tc.err
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			h.Export(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestTransaction_Export_Aborted(t *testing.T) {
+	mockSvc := &stubService{
+		export: func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+			if err := write(transaction.ExportRow{ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"}); err != nil {
+				return err
+			}
+			return errors.New("some error")
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/export?currency=CAD", nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.Export(w, req)
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,61 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"golang.org/x/exp/slog"
+)
+
+type idempotencyStore interface {
+	Reserve(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error)
+	Release(ctx context.Context, res idempotency.Reservation) error
+}
+
+// reserveIdempotencyKey claims the idempotency key for the request and returns the reservation. When the key
+// was already used, it responds with the original response or a conflict and returns nil.
+func (h *Handler) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key string, body []byte) *idempotency.Reservation {
+	if len(key) > idempotency.MaxKeyLength {
+		err := errors.New("idempotency key must not exceed 255 characters")
+		httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+		httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+		return nil
+	}
+
+	requestHash := idempotency.HashRequest(body)
+
+	reservation, rec, err := h.keys.Reserve(r.Context(), key, requestHash)
+	if err != nil {
+		httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+		httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+		return nil
+	}
+
+	switch {
+	case reservation != nil:
+		return reservation
+	case rec.RequestHash != requestHash:
+		httpresponse.RespondWithError(w, http.StatusConflict, httpresponse.ErrIdempotencyKeyReused)
+		httpresponse.LogError(r.Context(), "Conflict", http.StatusConflict, httpresponse.ErrIdempotencyKeyReused)
+		return nil
+	case !rec.Completed():
+		httpresponse.RespondWithError(w, http.StatusConflict, httpresponse.ErrIdempotencyKeyInProgress)
+		httpresponse.LogError(r.Context(), "Conflict", http.StatusConflict, httpresponse.ErrIdempotencyKeyInProgress)
+		return nil
+	default:
+		httpresponse.RespondJSON(w, rec.StatusCode, json.RawMessage(rec.ResponseBody))
+		slog.InfoContext(r.Context(), "Replayed response for idempotency key", "key", key)
+		return nil
+	}
+}
+
+// releaseIdempotencyKey frees the idempotency key of a request that did not complete, so that it can be retried.
+func (h *Handler) releaseIdempotencyKey(ctx context.Context, res idempotency.Reservation) {
+	if err := h.keys.Release(ctx, res); err != nil {
+		slog.ErrorContext(ctx, "Failed to release idempotency key", slog.String("key", res.Key), slog.String("error", err.Error()))
+	}
+}
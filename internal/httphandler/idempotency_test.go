@@ -0,0 +1,200 @@
+package httphandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+type stubIdempotencyStore struct {
+	reserve          func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error)
+	releasedKey      string
+	releaseCtxErr    error
+	receivedReserved string
+}
+
+func (s *stubIdempotencyStore) Reserve(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+	s.receivedReserved = key
+	return s.reserve(ctx, key, requestHash)
+}
+
+func (s *stubIdempotencyStore) Release(ctx context.Context, res idempotency.Reservation) error {
+	s.releasedKey = res.Key
+	s.releaseCtxErr = ctx.Err()
+	return nil
+}
+
+const recordRequestBody = `{"description":"food","transaction_date":"2023-09-21T00:00:00Z","amount":23.12}`
+
+func TestTransaction_Store_IdempotencyKey(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	var received *idempotency.Reservation
+	mockSvc := &stubService{
+		create: func(ctx context.Context, input transaction.RecordRequest) (string, error) {
+			received = input.Idempotency
+			return id, nil
+		},
+	}
+
+	mockKeys := &stubIdempotencyStore{
+		reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+			return &idempotency.Reservation{Key: key, RequestHash: requestHash}, nil, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBufferString(recordRequestBody))
+	req.Header.Set(idempotency.HeaderKey, "key-1")
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, mockKeys)
+	h.Store(w, req)
+
+	wantBody, _ := json.Marshal(transaction.RecordResponse{ID: id})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, string(wantBody), w.Body.String())
+	assert.Equal(t, "key-1", mockKeys.receivedReserved)
+	assert.Equal(t, &idempotency.Reservation{Key: "key-1", RequestHash: idempotency.HashRequest([]byte(recordRequestBody))}, received)
+	assert.Empty(t, mockKeys.releasedKey)
+}
+
+func TestTransaction_Store_IdempotencyKey_Replay(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	mockSvc := &stubService{
+		create: func(ctx context.Context, input transaction.RecordRequest) (string, error) {
+			t.Fatal("service must not be called when replaying a response")
+			return "", nil
+		},
+	}
+
+	mockKeys := &stubIdempotencyStore{
+		reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+			return nil, &idempotency.Record{
+				Key:          key,
+				RequestHash:  idempotency.HashRequest([]byte(recordRequestBody)),
+				StatusCode:   http.StatusCreated,
+				ResponseBody: []byte(`{"id":"` + id + `"}`),
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBufferString(recordRequestBody))
+	req.Header.Set(idempotency.HeaderKey, "key-1")
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, mockKeys)
+	h.Store(w, req)
+
+	var got transaction.RecordResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, transaction.RecordResponse{ID: id}, got)
+	assert.Empty(t, mockKeys.releasedKey)
+}
+
+func TestTransaction_Store_IdempotencyKey_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		key            string
+		reserve        func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error)
+		create         func(ctx context.Context, input transaction.RecordRequest) (string, error)
+		wantStatusCode int
+		wantReleased   bool
+	}{
+		"key too long": {
+			key:            string(make([]byte, idempotency.MaxKeyLength+1)),
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"store error": {
+			key: "key-1",
+			reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+				return nil, nil, someErr
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"same key with a different payload": {
+			key: "key-1",
+			reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+				return nil, &idempotency.Record{Key: key, RequestHash: "other", StatusCode: http.StatusCreated}, nil
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		"same key still in progress": {
+			key: "key-1",
+			reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+				return nil, &idempotency.Record{Key: key, RequestHash: requestHash}, nil
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		"service error releases the key": {
+			key: "key-1",
+			reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+				return &idempotency.Reservation{Key: key, RequestHash: requestHash}, nil, nil
+			},
+			create: func(ctx context.Context, input transaction.RecordRequest) (string, error) {
+				return "", httpresponse.ErrValidation
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantReleased:   true,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{create: tc.create}
+			mockKeys := &stubIdempotencyStore{reserve: tc.reserve}
+
+			req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBufferString(recordRequestBody))
+			req.Header.Set(idempotency.HeaderKey, tc.key)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, mockKeys)
+			h.Store(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, tc.wantReleased, mockKeys.releasedKey == tc.key)
+		})
+	}
+}
+
+func TestTransaction_Store_IdempotencyKey_ReleaseAfterCancel(t *testing.T) {
+	mockSvc := &stubService{
+		create: func(ctx context.Context, input transaction.RecordRequest) (string, error) {
+			return "", ctx.Err()
+		},
+	}
+
+	mockKeys := &stubIdempotencyStore{
+		reserve: func(ctx context.Context, key, requestHash string) (*idempotency.Reservation, *idempotency.Record, error) {
+			return &idempotency.Reservation{Key: key, RequestHash: requestHash}, nil, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBufferString(recordRequestBody)).WithContext(ctx)
+	req.Header.Set(idempotency.HeaderKey, "key-1")
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, mockKeys)
+	h.Store(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "key-1", mockKeys.releasedKey)
+	assert.NoError(t, mockKeys.releaseCtxErr)
+}
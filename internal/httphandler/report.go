@@ -0,0 +1,45 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+	"golang.org/x/exp/slog"
+)
+
+// Summary summarizes the transactions by month, week or day, in USD and converted to a target currency.
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	input := transaction.SummaryRequest{
+		GroupBy:        query.Get("group_by"),
+		From:           query.Get("from"),
+		To:             query.Get("to"),
+		Country:        query.Get("country"),
+		Currency:       query.Get("currency"),
+		RatePolicy:     query.Get("rate_policy"),
+		LookbackMonths: query.Get("lookback_months"),
+	}
+
+	res, err := h.svc.Summary(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, httpresponse.ErrValidation):
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		case errors.Is(err, httpresponse.ErrServiceUnavailable):
+			httpresponse.RespondWithError(w, http.StatusServiceUnavailable, err)
+			httpresponse.LogError(r.Context(), "Service unavailable", http.StatusServiceUnavailable, err)
+			return
+		default:
+			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transactions summarized successfully", "periods", len(res.Data), "group_by", res.GroupBy)
+}
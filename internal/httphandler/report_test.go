@@ -0,0 +1,121 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"github.com/vickiliou/challenge-wex/internal/transaction"
+)
+
+func TestTransaction_Summary(t *testing.T) {
+	want := transaction.SummaryResponse{
+		GroupBy:  transaction.GroupByMonth,
+		Currency: "CAD",
+		Data: []transaction.SummaryPeriod{
+			{
+				Period: "2023-09",
+				Start:  "2023-09-01",
+				End:    "2023-09-30",
+				Original: transaction.AmountSummary{
+					Count:   2,
+					Total:   money.MustParse("30.00", ""),
+					Min:     money.MustParse("10.00", ""),
+					Max:     money.MustParse("20.00", ""),
+					Average: money.MustParse("15.00", ""),
+				},
+				Converted: &transaction.AmountSummary{
+					Count:   1,
+					Total:   money.MustParse("13.50", ""),
+					Min:     money.MustParse("13.50", ""),
+					Max:     money.MustParse("13.50", ""),
+					Average: money.MustParse("13.50", ""),
+				},
+				Unconverted: 1,
+			},
+		},
+	}
+
+	mockSvc := &stubService{
+		summary: func(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error) {
+			return &want, nil
+		},
+	}
+
+	path := "/reports/summary?group_by=month&from=2023-09-01&to=2023-09-30&country=Canada&currency=Dollar&rate_policy=nearest&lookback_months=3"
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	h.Summary(w, req)
+
+	var got transaction.SummaryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	assert.NoError(t, err)
+
+	wantSummaryRequest := transaction.SummaryRequest{
+		GroupBy:        "month",
+		From:           "2023-09-01",
+		To:             "2023-09-30",
+		Country:        "Canada",
+		Currency:       "Dollar",
+		RatePolicy:     "nearest",
+		LookbackMonths: "3",
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+	assert.Equal(t, wantSummaryRequest, mockSvc.receivedSummaryRequest)
+}
+
+func TestTransaction_Summary_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mockSvc        *stubService
+		wantStatusCode int
+	}{
+		"validation error": {
+			mockSvc: &stubService{
+				summary: func(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error) {
+					return nil, httpresponse.ErrValidation
+				},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"exchange rate service unavailable": {
+			mockSvc: &stubService{
+				summary: func(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error) {
+					return nil, httpresponse.ErrServiceUnavailable
+				},
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+		"service error": {
+			mockSvc: &stubService{
+				summary: func(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error) {
+					return nil, someErr
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/reports/summary", nil)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(tc.mockSvc, nil)
+			h.Summary(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
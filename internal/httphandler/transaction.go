@@ -4,69 +4,194 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
 	"github.com/vickiliou/challenge-wex/internal/transaction"
 	"golang.org/x/exp/slog"
 )
 
 type service interface {
 	Create(ctx context.Context, input transaction.RecordRequest) (string, error)
+	Import(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error)
 	Get(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error)
+	List(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error)
+	ConvertBatch(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error)
+	Export(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error
+	Update(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error)
+	Delete(ctx context.Context, input transaction.DeleteRequest) error
+	History(ctx context.Context, id string) (*transaction.HistoryResponse, error)
+	Summary(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error)
 }
 
+// MaxImportSize is the largest import file accepted, in bytes.
+const MaxImportSize = 64 << 20
+
+// MaxRecordSize is the largest request body accepted when creating a transaction, in bytes.
+const MaxRecordSize = 64 << 10
+
+// HeaderActor is the header identifying who changes a transaction, recorded in its history.
+const HeaderActor = "X-Actor"
+
 // Handler is responsible for handling HTTP requests related to transactions.
 type Handler struct {
-	svc service
+	svc  service
+	keys idempotencyStore
 }
 
-// NewHandler creates a new transaction handler with the given service.
-func NewHandler(svc service) *Handler {
+// NewHandler creates a new transaction handler with the given service and idempotency key store.
+func NewHandler(svc service, keys idempotencyStore) *Handler {
 	return &Handler{
-		svc: svc,
+		svc:  svc,
+		keys: keys,
 	}
 }
 
 // Store handles the creation of a new transaction.
+// A request with an Idempotency-Key header creates the transaction at most once: retries with the same key
+// and payload replay the original response, stored together with the transaction.
 func (h *Handler) Store(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRecordSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = fmt.Errorf("request body must not exceed %d bytes", tooLarge.Limit)
+			httpresponse.RespondWithError(w, http.StatusRequestEntityTooLarge, err)
+			httpresponse.LogError(r.Context(), "Request body too large", http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		httpresponse.RespondWithError(w, http.StatusBadRequest, httpresponse.ErrInvalidRequestPayload)
+		httpresponse.LogError(r.Context(), "Error reading request body", http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		reservation *idempotency.Reservation
+		created     bool
+	)
+
+	if key := r.Header.Get(idempotency.HeaderKey); key != "" {
+		if reservation = h.reserveIdempotencyKey(w, r, key, body); reservation == nil {
+			return
+		}
+
+		// The key is completed together with the creation of the transaction, and only released when nothing
+		// was created. The release must outlive the request, whose context is cancelled if the client goes away.
+		defer func() {
+			if !created {
+				h.releaseIdempotencyKey(context.WithoutCancel(r.Context()), *reservation)
+			}
+		}()
+	}
+
 	var input transaction.RecordRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+	if err := json.Unmarshal(body, &input); err != nil {
+		if errors.Is(err, money.ErrPrecision) || errors.Is(err, money.ErrOverflow) {
+			err = fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		}
+
 		httpresponse.RespondWithError(w, http.StatusBadRequest, httpresponse.ErrInvalidRequestPayload)
-		httpresponse.LogError("Error decoding request body", http.StatusBadRequest, err)
+		httpresponse.LogError(r.Context(), "Error decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	input.Idempotency = reservation
 
 	id, err := h.svc.Create(r.Context(), input)
 	if err != nil {
 		switch {
 		case errors.Is(err, httpresponse.ErrValidation):
 			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
-			httpresponse.LogError("Validation error", http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
 			return
 		default:
 			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
-			httpresponse.LogError("Unexpected error", http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
 			return
 		}
 	}
 
+	created = true
+
 	res := transaction.RecordResponse{
 		ID: id,
 	}
 
 	httpresponse.RespondJSON(w, http.StatusCreated, res)
-	slog.Info("Transaction created successfully", "ID", id)
+	slog.InfoContext(r.Context(), "Transaction created successfully", "ID", id)
+}
+
+// Import creates the transactions of a CSV or JSONL file and reports the outcome of each row.
+// The format is given by the format query parameter, or else by the Content-Type of the request.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	input := transaction.ImportRequest{
+		Format: importFormat(r),
+		Body:   http.MaxBytesReader(w, r.Body, MaxImportSize),
+	}
+
+	res, err := h.svc.Import(r.Context(), input)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, httpresponse.ErrValidation):
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		case errors.As(err, &tooLarge):
+			err = fmt.Errorf("import file must not exceed %d bytes", tooLarge.Limit)
+			httpresponse.RespondWithError(w, http.StatusRequestEntityTooLarge, err)
+			httpresponse.LogError(r.Context(), "Request too large", http.StatusRequestEntityTooLarge, err)
+			return
+		default:
+			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transactions imported successfully", "accepted", res.Accepted, "rejected", res.Rejected)
+}
+
+// importFormat returns the format of an import file from the format query parameter or the Content-Type header.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return transaction.ImportCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return transaction.ImportJSONL
+	}
+
+	return ""
 }
 
 // Retrieve retrieves a transaction by its ID.
+// The response carries an ETag covering the version of the transaction and the exchange rate it was converted with,
+// and is not sent again when it matches If-None-Match.
 func (h *Handler) Retrieve(w http.ResponseWriter, r *http.Request) {
 	input := transaction.RetrieveRequest{
-		ID:       chi.URLParam(r, "id"),
-		Country:  r.URL.Query().Get("country"),
-		Currency: r.URL.Query().Get("currency"),
+		ID:             chi.URLParam(r, "id"),
+		Country:        r.URL.Query().Get("country"),
+		Currency:       r.URL.Query().Get("currency"),
+		RatePolicy:     r.URL.Query().Get("rate_policy"),
+		LookbackMonths: r.URL.Query().Get("lookback_months"),
+		IfNoneMatch:    r.Header.Get("If-None-Match"),
 	}
 
 	res, err := h.svc.Get(r.Context(), input)
@@ -74,23 +199,186 @@ func (h *Handler) Retrieve(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, httpresponse.ErrValidation):
 			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
-			httpresponse.LogError("Validation error", http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
 			return
 		case errors.Is(err, httpresponse.ErrNotFound):
 			httpresponse.RespondWithError(w, http.StatusNotFound, err)
-			httpresponse.LogError("Not found", http.StatusNotFound, err)
+			httpresponse.LogError(r.Context(), "Not found", http.StatusNotFound, err)
 			return
 		case errors.Is(err, httpresponse.ErrNoCurrencyConversion):
 			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
-			httpresponse.LogError("Bad request", http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Bad request", http.StatusBadRequest, err)
+			return
+		case errors.Is(err, httpresponse.ErrServiceUnavailable):
+			httpresponse.RespondWithError(w, http.StatusServiceUnavailable, err)
+			httpresponse.LogError(r.Context(), "Service unavailable", http.StatusServiceUnavailable, err)
 			return
 		default:
 			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
-			httpresponse.LogError("Unexpected error", http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
 			return
 		}
 	}
 
+	if res.NotModified {
+		httpresponse.NotModified(w, res.ETag)
+		slog.InfoContext(r.Context(), "Transaction not modified")
+		return
+	}
+
+	w.Header().Set("ETag", res.ETag)
 	httpresponse.RespondJSON(w, http.StatusOK, res)
-	slog.Info("Transaction retrieved successfully")
+	slog.InfoContext(r.Context(), "Transaction retrieved successfully")
+}
+
+// List lists the transactions matching the query filters, one page at a time.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	input := transaction.ListRequest{
+		From:        query.Get("from"),
+		To:          query.Get("to"),
+		MinAmount:   query.Get("min_amount"),
+		MaxAmount:   query.Get("max_amount"),
+		Description: query.Get("description"),
+		Sort:        query.Get("sort"),
+		Limit:       query.Get("limit"),
+		Cursor:      query.Get("cursor"),
+	}
+
+	res, err := h.svc.List(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, httpresponse.ErrValidation):
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		default:
+			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transactions listed successfully", "count", len(res.Data))
+}
+
+// Convert converts many transactions to a target currency in one request.
+func (h *Handler) Convert(w http.ResponseWriter, r *http.Request) {
+	var input transaction.ConversionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		httpresponse.RespondWithError(w, http.StatusBadRequest, httpresponse.ErrInvalidRequestPayload)
+		httpresponse.LogError(r.Context(), "Error decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.svc.ConvertBatch(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, httpresponse.ErrValidation):
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		default:
+			httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+			httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transactions converted successfully", "count", len(res.Data))
+}
+
+// Update corrects the fields of a transaction given in the request body.
+// With an If-Match header, the transaction is only updated if it is still at the version of the ETag.
+// The response carries the version-only ETag of the updated transaction, which validates later writes.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	version, ok := httpresponse.IfMatch(r)
+	if !ok {
+		respondChangeError(w, r, httpresponse.ErrPreconditionFailed)
+		return
+	}
+
+	var input transaction.UpdateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		if errors.Is(err, money.ErrPrecision) || errors.Is(err, money.ErrOverflow) {
+			err = fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+			httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+			httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+			return
+		}
+
+		httpresponse.RespondWithError(w, http.StatusBadRequest, httpresponse.ErrInvalidRequestPayload)
+		httpresponse.LogError(r.Context(), "Error decoding request body", http.StatusBadRequest, err)
+		return
+	}
+	input.ID = chi.URLParam(r, "id")
+	input.Actor = r.Header.Get(HeaderActor)
+	input.Version = version
+
+	res, err := h.svc.Update(r.Context(), input)
+	if err != nil {
+		respondChangeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", httpresponse.FormatETag(res.Version))
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transaction updated successfully", "ID", res.ID)
+}
+
+// Delete soft deletes a transaction, only if it is still at the version of the If-Match header if any.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	version, ok := httpresponse.IfMatch(r)
+	if !ok {
+		respondChangeError(w, r, httpresponse.ErrPreconditionFailed)
+		return
+	}
+
+	input := transaction.DeleteRequest{
+		ID:      chi.URLParam(r, "id"),
+		Actor:   r.Header.Get(HeaderActor),
+		Version: version,
+	}
+
+	if err := h.svc.Delete(r.Context(), input); err != nil {
+		respondChangeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "Transaction deleted successfully", "ID", input.ID)
+}
+
+// History lists the prior versions of a transaction.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.History(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondChangeError(w, r, err)
+		return
+	}
+
+	httpresponse.RespondJSON(w, http.StatusOK, res)
+	slog.InfoContext(r.Context(), "Transaction history retrieved successfully", "count", len(res.Data))
+}
+
+// respondChangeError responds with the status matching an error of the update, delete or history of a transaction.
+func respondChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, httpresponse.ErrValidation):
+		httpresponse.RespondWithError(w, http.StatusBadRequest, err)
+		httpresponse.LogError(r.Context(), "Validation error", http.StatusBadRequest, err)
+	case errors.Is(err, httpresponse.ErrNotFound):
+		httpresponse.RespondWithError(w, http.StatusNotFound, err)
+		httpresponse.LogError(r.Context(), "Not found", http.StatusNotFound, err)
+	case errors.Is(err, httpresponse.ErrPreconditionFailed):
+		httpresponse.RespondWithError(w, http.StatusPreconditionFailed, err)
+		httpresponse.LogError(r.Context(), "Precondition failed", http.StatusPreconditionFailed, err)
+	default:
+		httpresponse.RespondWithError(w, http.StatusInternalServerError, err)
+		httpresponse.LogError(r.Context(), "Unexpected error", http.StatusInternalServerError, err)
+	}
 }
@@ -6,14 +6,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
 	"github.com/vickiliou/challenge-wex/internal/transaction"
 )
 
@@ -22,6 +25,22 @@ type stubService struct {
 	create                   func(ctx context.Context, input transaction.RecordRequest) (string, error)
 	receivedRetrievedRequest transaction.RetrieveRequest
 	get                      func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error)
+	receivedListRequest      transaction.ListRequest
+	list                     func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error)
+	receivedConversion       transaction.ConversionRequest
+	convertBatch             func(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error)
+	receivedUpdateRequest    transaction.UpdateRequest
+	receivedSummaryRequest   transaction.SummaryRequest
+	summary                  func(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error)
+	receivedExportRequest    transaction.ExportRequest
+	export                   func(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error
+	receivedImportRequest    transaction.ImportRequest
+	importFile               func(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error)
+	update                   func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error)
+	receivedDeleteRequest    transaction.DeleteRequest
+	delete                   func(ctx context.Context, input transaction.DeleteRequest) error
+	receivedHistoryID        string
+	history                  func(ctx context.Context, id string) (*transaction.HistoryResponse, error)
 }
 
 func (s *stubService) Create(ctx context.Context, input transaction.RecordRequest) (string, error) {
@@ -34,6 +53,46 @@ func (s *stubService) Get(ctx context.Context, input transaction.RetrieveRequest
 	return s.get(ctx, input)
 }
 
+func (s *stubService) List(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+	s.receivedListRequest = input
+	return s.list(ctx, input)
+}
+
+func (s *stubService) ConvertBatch(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error) {
+	s.receivedConversion = input
+	return s.convertBatch(ctx, input)
+}
+
+func (s *stubService) Summary(ctx context.Context, input transaction.SummaryRequest) (*transaction.SummaryResponse, error) {
+	s.receivedSummaryRequest = input
+	return s.summary(ctx, input)
+}
+
+func (s *stubService) Export(ctx context.Context, input transaction.ExportRequest, write func(transaction.ExportRow) error) error {
+	s.receivedExportRequest = input
+	return s.export(ctx, input, write)
+}
+
+func (s *stubService) Import(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error) {
+	s.receivedImportRequest = input
+	return s.importFile(ctx, input)
+}
+
+func (s *stubService) Update(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+	s.receivedUpdateRequest = input
+	return s.update(ctx, input)
+}
+
+func (s *stubService) Delete(ctx context.Context, input transaction.DeleteRequest) error {
+	s.receivedDeleteRequest = input
+	return s.delete(ctx, input)
+}
+
+func (s *stubService) History(ctx context.Context, id string) (*transaction.HistoryResponse, error) {
+	s.receivedHistoryID = id
+	return s.history(ctx, id)
+}
+
 func TestTransaction_Store(t *testing.T) {
 	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 
@@ -46,14 +105,14 @@ func TestTransaction_Store(t *testing.T) {
 	input := transaction.RecordRequest{
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-		Amount:          23.12,
+		Amount:          money.MustParse("23.12", ""),
 	}
 	body, _ := json.Marshal(input)
 
 	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
-	h := NewHandler(mockSvc)
+	h := NewHandler(mockSvc, nil)
 	h.Store(w, req)
 
 	var got transaction.RecordResponse
@@ -89,12 +148,19 @@ func TestTransaction_Store_Error(t *testing.T) {
 			},
 			wantStatusCode: http.StatusBadRequest,
 		},
+		"amount with more than two decimal places": {
+			reqBody: func() []byte {
+				return []byte(`{"description":"food","transaction_date":"2023-09-21T00:00:00Z","amount":9.5579}`)
+			},
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
 		"validation error": {
 			reqBody: func() []byte {
 				jsonValue, _ := json.Marshal(
 					transaction.RecordRequest{
 						Description: "food",
-						Amount:      23.12,
+						Amount:      money.MustParse("23.12", ""),
 					})
 				return jsonValue
 			},
@@ -111,7 +177,7 @@ func TestTransaction_Store_Error(t *testing.T) {
 					transaction.RecordRequest{
 						Description:     "food",
 						TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-						Amount:          23.12,
+						Amount:          money.MustParse("23.12", ""),
 					})
 				return jsonValue
 			},
@@ -122,6 +188,13 @@ func TestTransaction_Store_Error(t *testing.T) {
 			},
 			wantStatusCode: http.StatusInternalServerError,
 		},
+		"request body too large": {
+			reqBody: func() []byte {
+				return []byte(`{"description":"` + strings.Repeat("a", MaxRecordSize) + `"}`)
+			},
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+		},
 	}
 
 	for title, tc := range testCases {
@@ -129,7 +202,7 @@ func TestTransaction_Store_Error(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(tc.reqBody()))
 			w := httptest.NewRecorder()
 
-			h := NewHandler(tc.mockSvc)
+			h := NewHandler(tc.mockSvc, nil)
 			h.Store(w, req)
 
 			assert.Equal(t, tc.wantStatusCode, w.Code)
@@ -137,6 +210,88 @@ func TestTransaction_Store_Error(t *testing.T) {
 	}
 }
 
+func TestTransaction_Import(t *testing.T) {
+	want := transaction.ImportResponse{
+		Accepted: 1,
+		Rejected: 1,
+		Rows: []transaction.ImportRow{
+			{Line: 2, ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+			{Line: 3, Error: "description is required"},
+		},
+	}
+
+	testCases := map[string]struct {
+		path        string
+		contentType string
+		wantFormat  string
+	}{
+		"csv content type":   {path: "/transactions/import", contentType: "text/csv; charset=utf-8", wantFormat: transaction.ImportCSV},
+		"jsonl content type": {path: "/transactions/import", contentType: "application/x-ndjson", wantFormat: transaction.ImportJSONL},
+		"format parameter":   {path: "/transactions/import?format=jsonl", contentType: "text/plain", wantFormat: transaction.ImportJSONL},
+		"unknown format":     {path: "/transactions/import", contentType: "text/plain", wantFormat: ""},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			var gotBody string
+			mockSvc := &stubService{
+				importFile: func(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error) {
+					b, _ := io.ReadAll(input.Body)
+					gotBody = string(b)
+					return &want, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString("some file"))
+			req.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			h.Import(w, req)
+
+			var got transaction.ImportResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, want, got)
+			assert.Equal(t, tc.wantFormat, mockSvc.receivedImportRequest.Format)
+			assert.Equal(t, "some file", gotBody)
+		})
+	}
+}
+
+func TestTransaction_Import_Error(t *testing.T) {
+	testCases := map[string]struct {
+		err            error
+		wantStatusCode int
+	}{
+		"validation error": {err: httpresponse.ErrValidation, wantStatusCode: http.StatusBadRequest},
+		"file too large":   {err: fmt.Errorf("error reading import file: %w", &http.MaxBytesError{Limit: MaxImportSize}), wantStatusCode: http.StatusRequestEntityTooLarge},
+		"unexpected error": {err: errors.New("some error"), wantStatusCode: http.StatusInternalServerError},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{
+				importFile: func(ctx context.Context, input transaction.ImportRequest) (*transaction.ImportResponse, error) {
+					return nil, tc.err
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/transactions/import", bytes.NewBufferString("some file"))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			h.Import(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
 func TestTransaction_Retrieve(t *testing.T) {
 	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 	country := "Brazil"
@@ -146,9 +301,9 @@ func TestTransaction_Retrieve(t *testing.T) {
 		ID:              id,
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-		OriginalAmount:  23.12,
-		ExchangeRate:    3.456,
-		ConvertedAmount: 79.90,
+		OriginalAmount:  money.MustParse("23.12", ""),
+		ExchangeRate:    money.MustParseRate("3.456"),
+		ConvertedAmount: money.MustParse("79.90", ""),
 	}
 
 	mockSvc := &stubService{
@@ -157,11 +312,11 @@ func TestTransaction_Retrieve(t *testing.T) {
 		},
 	}
 
-	path := fmt.Sprintf("/transactions/%s?country=%s&currency=%s", id, country, currency)
+	path := fmt.Sprintf("/transactions/%s?country=%s&currency=%s&rate_policy=nearest&lookback_months=12", id, country, currency)
 	req := httptest.NewRequest(http.MethodGet, path, nil)
 	w := httptest.NewRecorder()
 
-	h := NewHandler(mockSvc)
+	h := NewHandler(mockSvc, nil)
 	r := chi.NewRouter()
 	r.HandleFunc("/transactions/{id}", h.Retrieve)
 	r.ServeHTTP(w, req)
@@ -172,9 +327,11 @@ func TestTransaction_Retrieve(t *testing.T) {
 	}
 
 	wantRetrievedRequest := transaction.RetrieveRequest{
-		ID:       id,
-		Country:  country,
-		Currency: currency,
+		ID:             id,
+		Country:        country,
+		Currency:       currency,
+		RatePolicy:     "nearest",
+		LookbackMonths: "12",
 	}
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -182,6 +339,49 @@ func TestTransaction_Retrieve(t *testing.T) {
 	assert.Equal(t, wantRetrievedRequest, mockSvc.receivedRetrievedRequest)
 }
 
+func TestTransaction_Retrieve_ETag(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	etag := `"2-1d5c2f0e9a7b4c38"`
+
+	testCases := map[string]struct {
+		ifNoneMatch    string
+		notModified    bool
+		wantStatusCode int
+	}{
+		"no If-None-Match":    {wantStatusCode: http.StatusOK},
+		"stale If-None-Match": {ifNoneMatch: `"1-1d5c2f0e9a7b4c38"`, wantStatusCode: http.StatusOK},
+		"not modified":        {ifNoneMatch: etag, notModified: true, wantStatusCode: http.StatusNotModified},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{
+				get: func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error) {
+					return &transaction.RetrieveResponse{ID: id, Version: 2, ETag: etag, NotModified: tc.notModified}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/transactions/"+id+"?currency=CAD", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			r := chi.NewRouter()
+			r.HandleFunc("/transactions/{id}", h.Retrieve)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			assert.Equal(t, tc.ifNoneMatch, mockSvc.receivedRetrievedRequest.IfNoneMatch)
+			if tc.wantStatusCode == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
 func TestTransaction_Retrieve_Error(t *testing.T) {
 	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 	country := "Brazil"
@@ -191,6 +391,7 @@ func TestTransaction_Retrieve_Error(t *testing.T) {
 	testCases := map[string]struct {
 		mockSvc        *stubService
 		wantStatusCode int
+		wantErr        string
 	}{
 		"validation error": {
 			mockSvc: &stubService{
@@ -215,6 +416,15 @@ func TestTransaction_Retrieve_Error(t *testing.T) {
 				},
 			},
 			wantStatusCode: http.StatusBadRequest,
+			wantErr:        "no currency conversion rate available for the purchase date under the selected rate policy",
+		},
+		"exchange rate service unavailable": {
+			mockSvc: &stubService{
+				get: func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error) {
+					return nil, fmt.Errorf("error calling exchange rate provider: %w", httpresponse.ErrServiceUnavailable)
+				},
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
 		},
 		"service error": {
 			mockSvc: &stubService{
@@ -233,12 +443,472 @@ func TestTransaction_Retrieve_Error(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, path, nil)
 			w := httptest.NewRecorder()
 
-			h := NewHandler(tc.mockSvc)
+			h := NewHandler(tc.mockSvc, nil)
 			r := chi.NewRouter()
 			r.HandleFunc("/transactions/{id}", h.Retrieve)
 			r.ServeHTTP(w, req)
 
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantErr)
+		})
+	}
+}
+
+func TestTransaction_List(t *testing.T) {
+	want := transaction.ListResponse{
+		Data: []transaction.TransactionResponse{
+			{
+				ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Description:     "food",
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Amount:          money.MustParse("23.12", ""),
+			},
+		},
+		NextCursor: "next",
+	}
+
+	mockSvc := &stubService{
+		list: func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+			return &want, nil
+		},
+	}
+
+	path := "/transactions?from=2023-09-01&to=2023-09-30&min_amount=10&max_amount=100&description=foo&sort=-amount&limit=10&cursor=abc"
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	h.List(w, req)
+
+	var got transaction.ListResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	assert.NoError(t, err)
+
+	wantListRequest := transaction.ListRequest{
+		From:        "2023-09-01",
+		To:          "2023-09-30",
+		MinAmount:   "10",
+		MaxAmount:   "100",
+		Description: "foo",
+		Sort:        "-amount",
+		Limit:       "10",
+		Cursor:      "abc",
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+	assert.Equal(t, wantListRequest, mockSvc.receivedListRequest)
+}
+
+func TestTransaction_List_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mockSvc        *stubService
+		wantStatusCode int
+	}{
+		"validation error": {
+			mockSvc: &stubService{
+				list: func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+					return nil, httpresponse.ErrValidation
+				},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			mockSvc: &stubService{
+				list: func(ctx context.Context, input transaction.ListRequest) (*transaction.ListResponse, error) {
+					return nil, someErr
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/transactions", nil)
+			w := httptest.NewRecorder()
+
+			h := NewHandler(tc.mockSvc, nil)
+			h.List(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
+func TestTransaction_Convert(t *testing.T) {
+	want := transaction.ConversionResponse{
+		Data: []transaction.ConversionResult{
+			{
+				ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Result: &transaction.RetrieveResponse{
+					ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+					Description:     "food",
+					TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+					OriginalAmount:  money.MustParse("23.12", ""),
+					ExchangeRate:    money.MustParseRate("3.456"),
+					ConvertedAmount: money.MustParse("79.90", ""),
+				},
+			},
+			{
+				ID:    "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+				Error: "not found",
+			},
+		},
+	}
+
+	mockSvc := &stubService{
+		convertBatch: func(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error) {
+			return &want, nil
+		},
+	}
+
+	input := transaction.ConversionRequest{
+		IDs:      []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42", "f3a1c2d4-0008-4148-99f6-9c8086a1dd42"},
+		Country:  "Brazil",
+		Currency: "Real",
+	}
+	body, _ := json.Marshal(input)
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions/conversions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	h.Convert(w, req)
+
+	var got transaction.ConversionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+	assert.Equal(t, input, mockSvc.receivedConversion)
+}
+
+func TestTransaction_Convert_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		reqBody        []byte
+		mockSvc        *stubService
+		wantStatusCode int
+	}{
+		"invalid json request body": {
+			reqBody:        []byte(`{"ids":`),
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"validation error": {
+			reqBody: []byte(`{"country":"Brazil","currency":"Real"}`),
+			mockSvc: &stubService{
+				convertBatch: func(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error) {
+					return nil, httpresponse.ErrValidation
+				},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			reqBody: []byte(`{"ids":["b62a64c9-0008-4148-99f6-9c8086a1dd42"],"country":"Brazil","currency":"Real"}`),
+			mockSvc: &stubService{
+				convertBatch: func(ctx context.Context, input transaction.ConversionRequest) (*transaction.ConversionResponse, error) {
+					return nil, someErr
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/transactions/conversions", bytes.NewReader(tc.reqBody))
+			w := httptest.NewRecorder()
+
+			h := NewHandler(tc.mockSvc, nil)
+			h.Convert(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
+func TestTransaction_Update(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	want := transaction.TransactionResponse{
+		ID:              id,
+		Description:     "groceries",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("25.00", ""),
+	}
+
+	mockSvc := &stubService{
+		update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+			res := want
+			res.Version = 3
+			return &res, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/transactions/"+id, bytes.NewBufferString(`{"description":"groceries","amount":25}`))
+	req.Header.Set(HeaderActor, "jane")
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	r := chi.NewRouter()
+	r.Patch("/transactions/{id}", h.Update)
+	r.ServeHTTP(w, req)
+
+	var got transaction.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+	assert.Equal(t, id, mockSvc.receivedUpdateRequest.ID)
+	assert.Equal(t, "jane", mockSvc.receivedUpdateRequest.Actor)
+	assert.Equal(t, 2, mockSvc.receivedUpdateRequest.Version)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Equal(t, "groceries", *mockSvc.receivedUpdateRequest.Description)
+	assert.Equal(t, money.MustParse("25", ""), *mockSvc.receivedUpdateRequest.Amount)
+	assert.Nil(t, mockSvc.receivedUpdateRequest.TransactionDate)
+}
+
+func TestTransaction_Update_ETag(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	mockSvc := &stubService{
+		update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+			return &transaction.TransactionResponse{ID: id, Version: 2}, nil
+		},
+		get: func(ctx context.Context, input transaction.RetrieveRequest) (*transaction.RetrieveResponse, error) {
+			etag := httpresponse.FormatETag(2, "1.25", "Canada-Dollar")
+			notModified := httpresponse.IfNoneMatch(input.IfNoneMatch, etag)
+			return &transaction.RetrieveResponse{ID: id, Version: 2, ETag: etag, NotModified: notModified}, nil
+		},
+	}
+
+	h := NewHandler(mockSvc, nil)
+	r := chi.NewRouter()
+	r.Get("/transactions/{id}", h.Retrieve)
+	r.Patch("/transactions/{id}", h.Update)
+
+	serve := func(method, header, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/transactions/"+id+"?currency=CAD", bytes.NewBufferString(`{"description":"groceries"}`))
+		req.Header.Set(HeaderActor, "jane")
+		if etag != "" {
+			req.Header.Set(header, etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	written := serve(http.MethodPatch, "", "").Header().Get("ETag")
+	assert.Equal(t, `"2"`, written)
+
+	w := serve(http.MethodPatch, "If-Match", written)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, mockSvc.receivedUpdateRequest.Version)
+
+	w = serve(http.MethodGet, "If-None-Match", written)
+	assert.Equal(t, http.StatusOK, w.Code, "the write ETag does not identify a converted representation")
+	read := w.Header().Get("ETag")
+	assert.NotEqual(t, written, read)
+
+	w = serve(http.MethodGet, "If-None-Match", read)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve(http.MethodPatch, "If-Match", read)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, mockSvc.receivedUpdateRequest.Version)
+}
+
+func TestTransaction_Update_Error(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		body           string
+		ifMatch        string
+		mockSvc        *stubService
+		wantStatusCode int
+	}{
+		"weak If-Match": {
+			body:           `{"description":"groceries"}`,
+			ifMatch:        `W/"2"`,
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusPreconditionFailed,
+		},
+		"version mismatch": {
+			body:    `{"description":"groceries"}`,
+			ifMatch: `"1"`,
+			mockSvc: &stubService{
+				update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+					return nil, httpresponse.ErrPreconditionFailed
+				},
+			},
+			wantStatusCode: http.StatusPreconditionFailed,
+		},
+		"invalid payload": {
+			body:           `{"description":`,
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"amount precision": {
+			body:           `{"amount":1.001}`,
+			mockSvc:        &stubService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"validation error": {
+			body: `{}`,
+			mockSvc: &stubService{
+				update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+					return nil, httpresponse.ErrValidation
+				},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"not found": {
+			body: `{"description":"groceries"}`,
+			mockSvc: &stubService{
+				update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+					return nil, httpresponse.ErrNotFound
+				},
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		"unexpected error": {
+			body: `{"description":"groceries"}`,
+			mockSvc: &stubService{
+				update: func(ctx context.Context, input transaction.UpdateRequest) (*transaction.TransactionResponse, error) {
+					return nil, someErr
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/transactions/"+id, bytes.NewBufferString(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(tc.mockSvc, nil)
+			r := chi.NewRouter()
+			r.Patch("/transactions/{id}", h.Update)
+			r.ServeHTTP(w, req)
+
 			assert.Equal(t, tc.wantStatusCode, w.Code)
 		})
 	}
 }
+
+func TestTransaction_Delete(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	testCases := map[string]struct {
+		ifMatch        string
+		wantVersion    int
+		err            error
+		wantStatusCode int
+	}{
+		"deleted":          {wantStatusCode: http.StatusNoContent},
+		"If-Match":         {ifMatch: `"4"`, wantVersion: 4, wantStatusCode: http.StatusNoContent},
+		"If-Match any":     {ifMatch: "*", wantStatusCode: http.StatusNoContent},
+		"version mismatch": {ifMatch: `"3"`, wantVersion: 3, err: httpresponse.ErrPreconditionFailed, wantStatusCode: http.StatusPreconditionFailed},
+		"validation error": {err: httpresponse.ErrValidation, wantStatusCode: http.StatusBadRequest},
+		"not found":        {err: httpresponse.ErrNotFound, wantStatusCode: http.StatusNotFound},
+		"unexpected error": {err: errors.New("some error"), wantStatusCode: http.StatusInternalServerError},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockSvc := &stubService{
+				delete: func(ctx context.Context, input transaction.DeleteRequest) error {
+					return tc.err
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/transactions/"+id, nil)
+			req.Header.Set(HeaderActor, "jane")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			h := NewHandler(mockSvc, nil)
+			r := chi.NewRouter()
+			r.Delete("/transactions/{id}", h.Delete)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, transaction.DeleteRequest{ID: id, Actor: "jane", Version: tc.wantVersion}, mockSvc.receivedDeleteRequest)
+		})
+	}
+}
+
+func TestTransaction_History(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	want := transaction.HistoryResponse{
+		ID: id,
+		Data: []transaction.Revision{
+			{
+				Description:     "food",
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Amount:          money.MustParse("23.12", ""),
+				Change:          transaction.ChangeUpdate,
+				ChangedBy:       "jane",
+				ChangedAt:       time.Date(2023, time.October, 2, 10, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	mockSvc := &stubService{
+		history: func(ctx context.Context, id string) (*transaction.HistoryResponse, error) {
+			return &want, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/"+id+"/history", nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	r := chi.NewRouter()
+	r.Get("/transactions/{id}/history", h.History)
+	r.ServeHTTP(w, req)
+
+	var got transaction.HistoryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, want, got)
+	assert.Equal(t, id, mockSvc.receivedHistoryID)
+}
+
+func TestTransaction_History_Error(t *testing.T) {
+	mockSvc := &stubService{
+		history: func(ctx context.Context, id string) (*transaction.HistoryResponse, error) {
+			return nil, httpresponse.ErrNotFound
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/b62a64c9-0008-4148-99f6-9c8086a1dd42/history", nil)
+	w := httptest.NewRecorder()
+
+	h := NewHandler(mockSvc, nil)
+	r := chi.NewRouter()
+	r.Get("/transactions/{id}/history", h.History)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
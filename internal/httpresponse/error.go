@@ -1,6 +1,7 @@
 package httpresponse
 
 import (
+	"context"
 	"errors"
 
 	"golang.org/x/exp/slog"
@@ -13,16 +14,29 @@ var (
 	// ErrNotFound indicates that a resource was not found.
 	ErrNotFound = errors.New("not found")
 
-	// ErrNoCurrencyConversion indicates that no currency conversion rate data was available within 6 months before the purchase date.
-	ErrNoCurrencyConversion = errors.New("no currency conversion rate available within 6 months before the purchase date")
+	// ErrNoCurrencyConversion indicates that the rate policy selected no currency conversion rate for the purchase date.
+	ErrNoCurrencyConversion = errors.New("no currency conversion rate available for the purchase date under the selected rate policy")
+
+	// ErrServiceUnavailable indicates that an upstream service is failing or temporarily disabled by a circuit breaker.
+	ErrServiceUnavailable = errors.New("exchange rate service temporarily unavailable")
+
+	// ErrPreconditionFailed indicates that a resource was changed since the version given in an If-Match header.
+	ErrPreconditionFailed = errors.New("transaction was changed since the version given in If-Match")
 
 	// ErrInvalidRequestPayload indicates that the http request payload is invalid.
 	ErrInvalidRequestPayload = errors.New("invalid request payload")
+
+	// ErrIdempotencyKeyReused indicates that an idempotency key was already used with a different request payload.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request payload")
+
+	// ErrIdempotencyKeyInProgress indicates that a request with the same idempotency key is still being processed.
+	ErrIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is still in progress")
 )
 
-// LogError logs an error with additional information.
-func LogError(msg string, statusCode int, err error) {
-	slog.Error(
+// LogError logs an error with additional information, and the trace of the request if ctx holds one.
+func LogError(ctx context.Context, msg string, statusCode int, err error) {
+	slog.ErrorContext(
+		ctx,
 		msg,
 		slog.Int("status_code", statusCode),
 		slog.String("error", err.Error()),
@@ -0,0 +1,77 @@
+package httpresponse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FormatETag returns the entity tag of a resource version, e.g. `"3"`. A representation that also depends on
+// other data, such as the exchange rate it was converted with, passes that data as variant: a digest of it is
+// appended to the version, e.g. `"3-1d5c2f0e9a7b4c38"`, so that the tag changes with the data too.
+//
+// Both shapes validate writes alike: IfMatch only compares the version, so the version-only tag returned by a
+// write and the tag of any representation read at that version are interchangeable in If-Match. The variant
+// digest only serves If-None-Match, where a version-only tag never matches a representation with a variant.
+func FormatETag(version int, variant ...string) string {
+	value := strconv.Itoa(version)
+	if len(variant) > 0 {
+		sum := sha256.Sum256([]byte(strings.Join(variant, "\x00")))
+		value += "-" + hex.EncodeToString(sum[:8])
+	}
+
+	return strconv.Quote(value)
+}
+
+// ParseETag returns the resource version of a strong entity tag formatted by FormatETag, with or without variant.
+func ParseETag(etag string) (int, bool) {
+	value, err := strconv.Unquote(strings.TrimSpace(etag))
+	if err != nil {
+		return 0, false
+	}
+
+	value, _, _ = strings.Cut(value, "-")
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// IfNoneMatch reports whether an If-None-Match header matches the entity tag, using the weak comparison of RFC 9110.
+func IfNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotModified responds that the representation identified by the entity tag has not changed.
+func NotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// IfMatch returns the resource version required by the If-Match header of the request, or zero when the header
+// is absent or "*". It reports false when the header cannot match any version, i.e. it is not a single strong
+// entity tag formatted by FormatETag.
+func IfMatch(r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	return ParseETag(header)
+}
@@ -0,0 +1,91 @@
+package httpresponse_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+func TestETag(t *testing.T) {
+	etag := httpresponse.FormatETag(3)
+	assert.Equal(t, `"3"`, etag)
+
+	got, ok := httpresponse.ParseETag(etag)
+	assert.True(t, ok)
+	assert.Equal(t, 3, got)
+}
+
+func TestETag_Variant(t *testing.T) {
+	etag := httpresponse.FormatETag(3, "2023-09-30", "1.35", "treasury")
+	assert.Regexp(t, `^"3-[0-9a-f]{16}"$`, etag)
+	assert.Equal(t, etag, httpresponse.FormatETag(3, "2023-09-30", "1.35", "treasury"))
+	assert.NotEqual(t, etag, httpresponse.FormatETag(3, "2023-12-31", "1.35", "treasury"))
+	assert.NotEqual(t, etag, httpresponse.FormatETag(3, "2023-09-30", "1.36", "treasury"))
+	assert.NotEqual(t, etag, httpresponse.FormatETag(3, "2023-09-30", "1.35", "static"))
+
+	got, ok := httpresponse.ParseETag(etag)
+	assert.True(t, ok)
+	assert.Equal(t, 3, got)
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	testCases := map[string]struct {
+		header string
+		want   bool
+	}{
+		"absent":    {},
+		"any":       {header: "*", want: true},
+		"same":      {header: `"3-abc"`, want: true},
+		"weak":      {header: `W/"3-abc"`, want: true},
+		"in a list": {header: `"2-abc", "3-abc"`, want: true},
+		"other":     {header: `"3-def"`},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			assert.Equal(t, tc.want, httpresponse.IfNoneMatch(tc.header, `"3-abc"`))
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	testCases := map[string]struct {
+		header      string
+		wantVersion int
+		wantOK      bool
+	}{
+		"absent":      {wantOK: true},
+		"any":         {header: "*", wantOK: true},
+		"strong":      {header: `"7"`, wantVersion: 7, wantOK: true},
+		"weak":        {header: `W/"7"`},
+		"unquoted":    {header: "7"},
+		"not numeric": {header: `"abc"`},
+		"variant":     {header: `"7-1d5c2f0e9a7b4c38"`, wantVersion: 7, wantOK: true},
+		"list":        {header: `"6", "7"`},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tc.header != "" {
+				r.Header.Set("If-Match", tc.header)
+			}
+
+			gotVersion, gotOK := httpresponse.IfMatch(r)
+			assert.Equal(t, tc.wantVersion, gotVersion)
+			assert.Equal(t, tc.wantOK, gotOK)
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	httpresponse.NotModified(w, `"3"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.Bytes())
+}
@@ -1,6 +1,7 @@
 package httpresponse
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 )
@@ -28,7 +29,7 @@ func RespondJSON(w http.ResponseWriter, statusCode int, body any) {
 
 	if err := json.NewEncoder(w).Encode(body); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		LogError("Error encoding response", http.StatusInternalServerError, err)
+		LogError(context.Background(), "Error encoding response", http.StatusInternalServerError, err)
 		return
 	}
 }
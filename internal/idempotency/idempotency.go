@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// HeaderKey is the request header carrying the idempotency key.
+const HeaderKey = "Idempotency-Key"
+
+// MaxKeyLength is the maximum length of an idempotency key.
+const MaxKeyLength = 255
+
+// Record represents a request made with an idempotency key and, once completed, the response sent for it.
+type Record struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+// Reservation identifies the claim of a request on an idempotency key. A reservation that timed out may be
+// taken over by another request, so the key alone does not tell whose reservation it is.
+type Reservation struct {
+	Key         string
+	RequestHash string
+	ReservedAt  time.Time
+}
+
+// Response represents the response to store for the request that holds a reservation.
+type Response struct {
+	Reservation
+	StatusCode int
+	Body       []byte
+}
+
+// Completed reports whether the response of the request has been stored.
+func (r *Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// HashRequest returns the hex encoded SHA-256 hash of a request body.
+func HashRequest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
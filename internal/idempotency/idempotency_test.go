@@ -0,0 +1,19 @@
+package idempotency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRequest(t *testing.T) {
+	got := HashRequest([]byte(`{"description":"food"}`))
+	assert.Len(t, got, 64)
+	assert.Equal(t, got, HashRequest([]byte(`{"description":"food"}`)))
+	assert.NotEqual(t, got, HashRequest([]byte(`{"description":"taxi"}`)))
+}
+
+func TestRecord_Completed(t *testing.T) {
+	assert.False(t, (&Record{Key: "key"}).Completed())
+	assert.True(t, (&Record{Key: "key", StatusCode: 201}).Completed())
+}
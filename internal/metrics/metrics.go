@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vickiliou/challenge-wex/internal/ratecache"
+)
+
+// namespace prefixes the name of every metric of the application.
+const namespace = "wex"
+
+// Outcomes of a call to the Treasury API.
+const (
+	outcomeSuccess = "success" // 2xx response
+	outcomeFailure = "failure" // any other response
+	outcomeError   = "error"   // no response, e.g. a network error, a timeout or an open circuit breaker
+)
+
+// unmatchedRoute is the route of the requests that matched no route, so that unknown paths cannot create series.
+const unmatchedRoute = "unmatched"
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type rateCache interface {
+	Stats() ratecache.Stats
+	Len() int
+}
+
+// Metrics records the metrics of the application and serves them in the Prometheus format, along with
+// the Go runtime and process metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	queryDuration    *prometheus.HistogramVec
+	queryErrors      *prometheus.CounterVec
+	treasuryDuration *prometheus.HistogramVec
+}
+
+// New creates the metrics of the application in a registry of their own.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests served, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests served, by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of the repository operations, by repository and operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"repository", "operation"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Number of repository operations that failed, by repository and operation.",
+		}, []string{"repository", "operation"}),
+		treasuryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "treasury_request_duration_seconds",
+			Help:      "Duration of the calls to the Treasury API, retries included, by outcome and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"outcome", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.queryErrors,
+		m.treasuryDuration,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the number and duration of the requests by method, chi route pattern and status code.
+// It must be used on the root router, so that the route pattern is known once the request is served.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		// Deferred so that aborted requests, which panic, are recorded too.
+		defer func() {
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+			m.httpRequests.With(labels).Inc()
+			m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// ObserveQuery records the duration of a repository operation, and counts it as failed if err is set.
+func (m *Metrics) ObserveQuery(repository, operation string, duration time.Duration, err error) {
+	m.queryDuration.WithLabelValues(repository, operation).Observe(duration.Seconds())
+
+	if err != nil {
+		m.queryErrors.WithLabelValues(repository, operation).Inc()
+	}
+}
+
+// RegisterDB records the connection pool statistics of a database under the given name.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterRateCache records the hits, misses and number of entries of the exchange rate cache.
+// The hit rate is the rate of the hits over the rate of both.
+func (m *Metrics) RegisterRateCache(c rateCache) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "rate_cache_requests_total",
+			Help:        "Number of exchange rate requests answered by the cache (hit) or by its source (miss).",
+			ConstLabels: prometheus.Labels{"result": "hit"},
+		}, func() float64 { return float64(c.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "rate_cache_requests_total",
+			Help:        "Number of exchange rate requests answered by the cache (hit) or by its source (miss).",
+			ConstLabels: prometheus.Labels{"result": "miss"},
+		}, func() float64 { return float64(c.Stats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rate_cache_entries",
+			Help:      "Number of exchange rates in the cache, including expired ones not evicted yet.",
+		}, func() float64 { return float64(c.Len()) }),
+	)
+}
+
+// InstrumentClient returns a client recording the duration, outcome and status code of the calls made with next.
+func (m *Metrics) InstrumentClient(next httpClient) *Client {
+	return &Client{
+		next:     next,
+		duration: m.treasuryDuration,
+	}
+}
+
+// Client is an HTTP client recording the calls it makes.
+type Client struct {
+	next     httpClient
+	duration *prometheus.HistogramVec
+}
+
+// Do sends the request with the wrapped client and records it.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	res, err := c.next.Do(req)
+
+	outcome, status := outcomeError, "none"
+	if err == nil {
+		outcome, status = outcomeFailure, strconv.Itoa(res.StatusCode)
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			outcome = outcomeSuccess
+		}
+	}
+
+	c.duration.WithLabelValues(outcome, status).Observe(time.Since(start).Seconds())
+
+	return res, err
+}
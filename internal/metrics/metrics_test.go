@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vickiliou/challenge-wex/internal/ratecache"
+)
+
+type stubClient struct {
+	do func(req *http.Request) (*http.Response, error)
+}
+
+func (s *stubClient) Do(req *http.Request) (*http.Response, error) {
+	return s.do(req)
+}
+
+type stubRateCache struct {
+	stats ratecache.Stats
+	len   int
+}
+
+func (s *stubRateCache) Stats() ratecache.Stats {
+	return s.stats
+}
+
+func (s *stubRateCache) Len() int {
+	return s.len
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	testCases := map[string]struct {
+		method     string
+		path       string
+		wantLabels []string
+	}{
+		"route pattern": {
+			method:     http.MethodGet,
+			path:       "/v1/transactions/f47ac10b-58cc-4372-a567-0e02b2c3d479",
+			wantLabels: []string{http.MethodGet, "/v1/transactions/{id}", "404"},
+		},
+		"implicit status": {
+			method:     http.MethodPost,
+			path:       "/v1/transactions",
+			wantLabels: []string{http.MethodPost, "/v1/transactions", "200"},
+		},
+		"unmatched route": {
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantLabels: []string{http.MethodGet, unmatchedRoute, "404"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := New()
+
+			r := chi.NewRouter()
+			r.Use(m.Middleware)
+			r.Get("/v1/transactions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+			r.Post("/v1/transactions", func(w http.ResponseWriter, r *http.Request) {})
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(tc.wantLabels...)))
+			assert.Equal(t, 1, testutil.CollectAndCount(m.httpDuration))
+		})
+	}
+}
+
+func TestMetrics_ObserveQuery(t *testing.T) {
+	m := New()
+
+	m.ObserveQuery("transactions", "create", time.Millisecond, nil)
+	m.ObserveQuery("transactions", "create", time.Millisecond, errors.New("database is locked"))
+	m.ObserveQuery("transactions", "list", time.Millisecond, nil)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.queryDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("transactions", "create")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("transactions", "list")))
+}
+
+func TestClient_Do(t *testing.T) {
+	testCases := map[string]struct {
+		res        *http.Response
+		err        error
+		wantLabels []string
+	}{
+		"success": {
+			res:        &http.Response{StatusCode: http.StatusOK},
+			wantLabels: []string{outcomeSuccess, "200"},
+		},
+		"failure": {
+			res:        &http.Response{StatusCode: http.StatusServiceUnavailable},
+			wantLabels: []string{outcomeFailure, "503"},
+		},
+		"error": {
+			err:        errors.New("connection refused"),
+			wantLabels: []string{outcomeError, "none"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := New()
+			c := m.InstrumentClient(&stubClient{
+				do: func(req *http.Request) (*http.Response, error) {
+					return tc.res, tc.err
+				},
+			})
+
+			res, err := c.Do(httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tc.res, res)
+			assert.Equal(t, tc.err, err)
+
+			assert.Equal(t, 1, testutil.CollectAndCount(m.treasuryDuration))
+			_, err = m.treasuryDuration.GetMetricWithLabelValues(tc.wantLabels...)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.RegisterRateCache(&stubRateCache{stats: ratecache.Stats{Hits: 3, Misses: 1}, len: 1})
+	m.RegisterDB(&sql.DB{}, "sqlite")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `wex_rate_cache_requests_total{result="hit"} 3`)
+	assert.Contains(t, body, `wex_rate_cache_requests_total{result="miss"} 1`)
+	assert.Contains(t, body, "wex_rate_cache_entries 1")
+	assert.Contains(t, body, `go_sql_open_connections{db_name="sqlite"} 0`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,248 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// USD is the currency code of every stored purchase transaction.
+const USD = "USD"
+
+// scale is the number of minor units in one major unit. Every amount is kept in hundredths.
+const scale = 100
+
+var (
+	// ErrInvalidAmount indicates that an amount is not a valid decimal number.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrPrecision indicates that an amount has more than two decimal places.
+	ErrPrecision = errors.New("amount must be rounded to two decimal places")
+
+	// ErrOverflow indicates that an amount does not fit in the supported range.
+	ErrOverflow = errors.New("amount out of range")
+
+	// ErrInvalidRate indicates that an exchange rate is missing or not a positive decimal number.
+	ErrInvalidRate = errors.New("invalid exchange rate")
+)
+
+// Money represents an exact monetary amount as an integer number of minor units (hundredths) of a currency.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// New creates a Money value from an amount in minor units and a currency code.
+func New(minor int64, currency string) Money {
+	return Money{
+		minor:    minor,
+		currency: currency,
+	}
+}
+
+// Parse parses a decimal string such as "100.50" into a Money value of the given currency.
+// It fails if the value has more than two significant decimal places.
+func Parse(s, currency string) (Money, error) {
+	if s == "" || strings.Trim(s, "0123456789.+-eE") != "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	r.Mul(r, big.NewRat(scale, 1))
+	if !r.IsInt() {
+		return Money{}, ErrPrecision
+	}
+
+	minor := r.Num()
+	if !minor.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return New(minor.Int64(), currency), nil
+}
+
+// MustParse is like Parse but panics if the value cannot be parsed.
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MinorUnits returns the amount in minor units.
+func (m Money) MinorUnits() int64 {
+	return m.minor
+}
+
+// Currency returns the currency code of the amount.
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// Convert multiplies the amount by the exchange rate and returns the result in the target currency,
+// rounded half away from zero to the nearest minor unit.
+func (m Money) Convert(rate Rate, currency string) (Money, error) {
+	if rate.IsZero() {
+		return Money{}, ErrInvalidRate
+	}
+
+	product := new(big.Int).Mul(big.NewInt(m.minor), rate.coef)
+	minor := roundDiv(product, pow10(rate.exp))
+	if !minor.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return New(minor.Int64(), currency), nil
+}
+
+// Divide divides the amount by n, which must be positive, rounded half away from zero to the nearest minor unit.
+func (m Money) Divide(n int64) Money {
+	minor := roundDiv(big.NewInt(m.minor), big.NewInt(n))
+	return New(minor.Int64(), m.currency)
+}
+
+// String returns the amount as a decimal string with two decimal places, e.g. "100.50".
+func (m Money) String() string {
+	sign := ""
+	minor := m.minor
+	if minor < 0 {
+		sign = "-"
+	}
+
+	abs := new(big.Int).Abs(big.NewInt(minor))
+	major, frac := new(big.Int).QuoRem(abs, big.NewInt(scale), new(big.Int))
+
+	return fmt.Sprintf("%s%s.%02d", sign, major.String(), frac.Int64())
+}
+
+// MarshalJSON encodes the amount as a JSON number with two decimal places.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number into the amount. The currency is left unchanged.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	parsed, err := Parse(string(data), m.currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Rate represents an exact decimal exchange rate as coef * 10^-exp.
+type Rate struct {
+	coef *big.Int
+	exp  int
+}
+
+// ParseRate parses a decimal string such as "1.356" into a Rate.
+func ParseRate(s string) (Rate, error) {
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	digits := intPart + fracPart
+	if intPart == "" || strings.Trim(digits, "0123456789") != "" {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+
+	return Rate{coef: coef, exp: len(fracPart)}, nil
+}
+
+// MustParseRate is like ParseRate but panics if the value cannot be parsed.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// IsZero reports whether the rate is unset or zero.
+func (r Rate) IsZero() bool {
+	return r.coef == nil || r.coef.Sign() == 0
+}
+
+// String returns the rate as a decimal string.
+func (r Rate) String() string {
+	if r.coef == nil {
+		return "0"
+	}
+
+	digits := r.coef.String()
+	if r.exp == 0 {
+		return digits
+	}
+
+	if len(digits) <= r.exp {
+		digits = strings.Repeat("0", r.exp-len(digits)+1) + digits
+	}
+
+	return digits[:len(digits)-r.exp] + "." + digits[len(digits)-r.exp:]
+}
+
+// MarshalJSON encodes the rate as a JSON number.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number into the rate.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	parsed, err := ParseRate(string(data))
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+	return nil
+}
+
+// roundDiv divides n by d, rounding half away from zero.
+func roundDiv(n, d *big.Int) *big.Int {
+	q, rem := new(big.Int).QuoRem(n, d, new(big.Int))
+
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	if twice.Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	return q
+}
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,157 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  int64
+	}{
+		"integer":             {input: "100", want: 10000},
+		"one decimal place":   {input: "100.5", want: 10050},
+		"exponent notation":   {input: "1.005e2", want: 10050},
+		"float edge case":     {input: "1.01", want: 101},
+		"trailing zeros":      {input: "20.4700", want: 2047},
+		"negative":            {input: "-5", want: -500},
+		"smallest minor unit": {input: "0.01", want: 1},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := Parse(tc.input, USD)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, New(tc.want, USD), got)
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	testCases := map[string]struct {
+		input   string
+		wantErr error
+	}{
+		"empty":                      {input: "", wantErr: ErrInvalidAmount},
+		"not a number":               {input: "abc", wantErr: ErrInvalidAmount},
+		"fraction":                   {input: "1/2", wantErr: ErrInvalidAmount},
+		"more than two decimals":     {input: "9.5579", wantErr: ErrPrecision},
+		"third decimal is not zero":  {input: "1.005", wantErr: ErrPrecision},
+		"larger than supported size": {input: "1e30", wantErr: ErrOverflow},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			_, gotErr := Parse(tc.input, USD)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	testCases := map[string]struct {
+		amount string
+		rate   string
+		want   string
+	}{
+		"exact":                     {amount: "10.00", rate: "5.0", want: "50.00"},
+		"round down":                {amount: "23.12", rate: "3.456", want: "79.90"},
+		"round half away from zero": {amount: "1.00", rate: "1.005", want: "1.01"},
+		"negative amount":           {amount: "-1.00", rate: "1.005", want: "-1.01"},
+		"rate below one":            {amount: "100.00", rate: "0.9175", want: "91.75"},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := MustParse(tc.amount, USD).Convert(MustParseRate(tc.rate), "CAD")
+			assert.NoError(t, gotErr)
+			assert.Equal(t, MustParse(tc.want, "CAD"), got)
+		})
+	}
+}
+
+func TestMoney_Convert_Error(t *testing.T) {
+	_, gotErr := MustParse("1.00", USD).Convert(Rate{}, "CAD")
+	assert.ErrorIs(t, gotErr, ErrInvalidRate)
+}
+
+func TestMoney_Divide(t *testing.T) {
+	testCases := map[string]struct {
+		amount string
+		n      int64
+		want   string
+	}{
+		"exact":                     {amount: "10.00", n: 4, want: "2.50"},
+		"round down":                {amount: "10.00", n: 3, want: "3.33"},
+		"round half away from zero": {amount: "0.05", n: 2, want: "0.03"},
+		"negative amount":           {amount: "-0.05", n: 2, want: "-0.03"},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got := MustParse(tc.amount, "CAD").Divide(tc.n)
+			assert.Equal(t, MustParse(tc.want, "CAD"), got)
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var got struct {
+		Amount Money `json:"amount"`
+	}
+
+	gotErr := json.Unmarshal([]byte(`{"amount": 100.5}`), &got)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, New(10050, ""), got.Amount)
+
+	body, gotErr := json.Marshal(got)
+	assert.NoError(t, gotErr)
+	assert.JSONEq(t, `{"amount": 100.50}`, string(body))
+	assert.Contains(t, string(body), "100.50")
+}
+
+func TestMoney_JSON_Error(t *testing.T) {
+	var got Money
+	gotErr := json.Unmarshal([]byte(`9.5579`), &got)
+	assert.ErrorIs(t, gotErr, ErrPrecision)
+}
+
+func TestParseRate(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  string
+	}{
+		"integer":             {input: "5", want: "5"},
+		"decimal":             {input: "1.356", want: "1.356"},
+		"leading zero":        {input: "0.009", want: "0.009"},
+		"keeps trailing zero": {input: "5.0", want: "5.0"},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := ParseRate(tc.input)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
+
+func TestParseRate_Error(t *testing.T) {
+	testCases := map[string]string{
+		"empty":        "",
+		"no integer":   ".5",
+		"negative":     "-1.2",
+		"exponent":     "1e3",
+		"not a number": "abc",
+	}
+
+	for title, input := range testCases {
+		t.Run(title, func(t *testing.T) {
+			_, gotErr := ParseRate(input)
+			assert.ErrorIs(t, gotErr, ErrInvalidRate)
+		})
+	}
+}
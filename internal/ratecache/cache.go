@@ -0,0 +1,180 @@
+package ratecache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"golang.org/x/sync/singleflight"
+)
+
+type source interface {
+	GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+}
+
+// entry represents a cached exchange rate, or the absence of a rate when err is set.
+type entry struct {
+	key       string
+	rate      *gateway.CurrencyExchangeRate
+	err       error
+	expiresAt time.Time
+}
+
+// Cache is an exchange rate source caching the rates of another source in memory.
+// Requests are keyed on the country currency, the rate policy and the rate window, so requests resolving
+// to the same window share an entry. Missing rates are cached for a shorter time, and concurrent misses of the same key make a single call.
+type Cache struct {
+	src         source
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // most recently used first
+	group   singleflight.Group
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Stats represents the number of requests a cache answered from its entries and from its source.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// New creates a cache of at most size rates in front of src. Rates are kept for ttl,
+// and ErrNoCurrencyConversion results for negativeTTL; a zero negativeTTL disables negative caching.
+func New(src source, size int, ttl, negativeTTL time.Duration) *Cache {
+	return &Cache{
+		src:         src,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element, size),
+		lru:         list.New(),
+	}
+}
+
+// GetExchangeRate returns the cached exchange rate of the request, fetching it from the source on a miss.
+// A caller whose context is done stops waiting, but the call to the source goes on for the other callers.
+func (c *Cache) GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	key := cacheKey(input)
+
+	if e, ok := c.get(key); ok {
+		c.hits.Add(1)
+		return copyRate(e.rate), e.err
+	}
+	c.misses.Add(1)
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		rate, err := c.src.GetExchangeRate(context.WithoutCancel(ctx), input)
+		switch {
+		case err == nil:
+			c.set(key, rate, nil, c.ttl)
+		case errors.Is(err, httpresponse.ErrNoCurrencyConversion) && c.negativeTTL > 0:
+			c.set(key, nil, err, c.negativeTTL)
+		}
+		return rate, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copyRate(res.Val.(*gateway.CurrencyExchangeRate)), nil
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not evicted yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Stats returns the number of hits and misses since the cache was created. Misses waiting on a call
+// made for another request count as misses.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// get returns the unexpired entry of the key.
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// set stores an entry, evicting the least recently used one when the cache is full.
+func (c *Cache) set(key string, rate *gateway.CurrencyExchangeRate, err error, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &entry{
+		key:       key,
+		rate:      copyRate(rate),
+		err:       err,
+		expiresAt: c.now().Add(ttl),
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// cacheKey identifies the requests sharing the same exchange rate.
+func cacheKey(input gateway.CurrencyExchangeRateRequest) string {
+	from, to := input.Window()
+	return fmt.Sprintf("%s|%s|%s|%s", input.CountryCurrencyDesc(), input.Policy, from.Format(time.DateOnly), to.Format(time.DateOnly))
+}
+
+// copyRate returns a copy of the rate so that callers cannot modify cached values.
+func copyRate(rate *gateway.CurrencyExchangeRate) *gateway.CurrencyExchangeRate {
+	if rate == nil {
+		return nil
+	}
+
+	res := *rate
+	return &res
+}
@@ -0,0 +1,267 @@
+package ratecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+type stubSource struct {
+	calls           atomic.Int32
+	getExchangeRate func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+}
+
+func (s *stubSource) GetExchangeRate(_ context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	s.calls.Add(1)
+	return s.getExchangeRate(input)
+}
+
+func rateOf(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	return &gateway.CurrencyExchangeRate{
+		CountryCurrencyDesc: input.CountryCurrencyDesc(),
+		ExchangeRate:        "1.35",
+		RecordDate:          "2023-09-30",
+	}, nil
+}
+
+func newTestCache(src source, size int) (*Cache, *time.Time) {
+	now := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	c := New(src, size, time.Hour, time.Minute)
+	c.now = func() time.Time { return now }
+
+	return c, &now
+}
+
+func request(currency string, day int) gateway.CurrencyExchangeRateRequest {
+	return gateway.CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.October, day, 12, 0, 0, 0, time.UTC),
+		Country:         "Canada",
+		Currency:        currency,
+	}
+}
+
+func TestCache_GetExchangeRate(t *testing.T) {
+	testCases := map[string]struct {
+		requests  []gateway.CurrencyExchangeRateRequest
+		elapsed   time.Duration
+		wantCalls int32
+	}{
+		"hit": {
+			requests:  []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), request("Dollar", 1)},
+			wantCalls: 1,
+		},
+		"same window at another time of day": {
+			requests: []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), {
+				TransactionDate: time.Date(2023, time.October, 1, 23, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+			}},
+			wantCalls: 1,
+		},
+		"different window": {
+			requests:  []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), request("Dollar", 2)},
+			wantCalls: 2,
+		},
+		"different lookback": {
+			requests: []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), {
+				TransactionDate: time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+				LookbackMonths:  12,
+			}},
+			wantCalls: 2,
+		},
+		"different policy": {
+			requests: []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), {
+				TransactionDate: time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+				Policy:          gateway.PolicyQuarterStart,
+			}},
+			wantCalls: 2,
+		},
+		"expired": {
+			requests:  []gateway.CurrencyExchangeRateRequest{request("Dollar", 1), request("Dollar", 1)},
+			elapsed:   time.Hour,
+			wantCalls: 2,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			src := &stubSource{getExchangeRate: rateOf}
+			c, now := newTestCache(src, 10)
+
+			for i, input := range tc.requests {
+				if i > 0 {
+					*now = now.Add(tc.elapsed)
+				}
+
+				got, gotErr := c.GetExchangeRate(context.Background(), input)
+				assert.NoError(t, gotErr)
+				assert.Equal(t, "Canada-Dollar", got.CountryCurrencyDesc)
+			}
+
+			assert.Equal(t, tc.wantCalls, src.calls.Load())
+			assert.Equal(t, Stats{Hits: uint64(len(tc.requests)) - uint64(tc.wantCalls), Misses: uint64(tc.wantCalls)}, c.Stats())
+		})
+	}
+}
+
+func TestCache_GetExchangeRate_Copy(t *testing.T) {
+	src := &stubSource{getExchangeRate: rateOf}
+	c, _ := newTestCache(src, 10)
+
+	got, gotErr := c.GetExchangeRate(context.Background(), request("Dollar", 1))
+	assert.NoError(t, gotErr)
+	got.Provider = "treasury"
+
+	got, gotErr = c.GetExchangeRate(context.Background(), request("Dollar", 1))
+	assert.NoError(t, gotErr)
+	assert.Empty(t, got.Provider)
+}
+
+func TestCache_GetExchangeRate_Error(t *testing.T) {
+	testCases := map[string]struct {
+		err         error
+		negativeTTL time.Duration
+		elapsed     time.Duration
+		wantCalls   int32
+	}{
+		"no conversion is cached": {
+			err:         httpresponse.ErrNoCurrencyConversion,
+			negativeTTL: time.Minute,
+			wantCalls:   1,
+		},
+		"no conversion expires": {
+			err:         httpresponse.ErrNoCurrencyConversion,
+			negativeTTL: time.Minute,
+			elapsed:     time.Minute,
+			wantCalls:   2,
+		},
+		"negative caching disabled": {
+			err:       httpresponse.ErrNoCurrencyConversion,
+			wantCalls: 2,
+		},
+		"unavailable is not cached": {
+			err:         httpresponse.ErrServiceUnavailable,
+			negativeTTL: time.Minute,
+			wantCalls:   2,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			src := &stubSource{getExchangeRate: func(gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+				return nil, tc.err
+			}}
+			c, now := newTestCache(src, 10)
+			c.negativeTTL = tc.negativeTTL
+
+			for i := 0; i < 2; i++ {
+				got, gotErr := c.GetExchangeRate(context.Background(), request("Dollar", 1))
+				assert.ErrorIs(t, gotErr, tc.err)
+				assert.Nil(t, got)
+				*now = now.Add(tc.elapsed)
+			}
+
+			assert.Equal(t, tc.wantCalls, src.calls.Load())
+		})
+	}
+}
+
+func TestCache_GetExchangeRate_Eviction(t *testing.T) {
+	src := &stubSource{getExchangeRate: rateOf}
+	c, _ := newTestCache(src, 2)
+	ctx := context.Background()
+
+	for _, input := range []gateway.CurrencyExchangeRateRequest{
+		request("Dollar", 1),
+		request("Dollar", 2),
+		request("Dollar", 1), // day 2 is now the least recently used
+		request("Dollar", 3),
+	} {
+		_, err := c.GetExchangeRate(ctx, input)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int32(3), src.calls.Load())
+
+	_, err := c.GetExchangeRate(ctx, request("Dollar", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), src.calls.Load())
+
+	_, err = c.GetExchangeRate(ctx, request("Dollar", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), src.calls.Load())
+}
+
+func TestCache_GetExchangeRate_Coalescing(t *testing.T) {
+	release := make(chan struct{})
+	src := &stubSource{getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+		<-release
+		return rateOf(input)
+	}}
+	c, _ := newTestCache(src, 10)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetExchangeRate(context.Background(), request("Dollar", 1))
+			errs <- err
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return src.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // let the other callers join the flight
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), src.calls.Load())
+}
+
+func TestCache_GetExchangeRate_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	src := &stubSource{getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+		<-release
+		return rateOf(input)
+	}}
+	c, _ := newTestCache(src, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := c.GetExchangeRate(ctx, request("Dollar", 1))
+		done <- err
+	}()
+
+	assert.Eventually(t, func() bool { return src.calls.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.True(t, errors.Is(<-done, context.Canceled))
+
+	// The call goes on and its result is cached for the next callers.
+	close(release)
+	assert.Eventually(t, func() bool { return c.Len() == 1 }, time.Second, time.Millisecond)
+
+	got, gotErr := c.GetExchangeRate(context.Background(), request("Dollar", 1))
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "1.35", got.ExchangeRate)
+	assert.Equal(t, int32(1), src.calls.Load())
+}
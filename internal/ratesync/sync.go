@@ -0,0 +1,94 @@
+package ratesync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"golang.org/x/exp/slog"
+)
+
+type gatewayExchangeRatePage interface {
+	GetExchangeRatePage(ctx context.Context, input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error)
+}
+
+type store interface {
+	Upsert(ctx context.Context, rates []gateway.CurrencyExchangeRate) error
+	LatestRecordDate(ctx context.Context) (time.Time, error)
+}
+
+// Syncer copies the Treasury Rates of Exchange dataset into the local exchange rate store.
+type Syncer struct {
+	gw       gatewayExchangeRatePage
+	store    store
+	pageSize int
+}
+
+// NewSyncer creates a new instance of the exchange rate syncer.
+func NewSyncer(gw gatewayExchangeRatePage, store store) *Syncer {
+	return &Syncer{
+		gw:       gw,
+		store:    store,
+		pageSize: gateway.DefaultPageSize,
+	}
+}
+
+// Sync pages through the dataset starting at the latest record date already stored and upserts every record.
+// The latest record date is fetched again so that corrections published for it are picked up.
+// It returns the number of records synced.
+func (s *Syncer) Sync(ctx context.Context) (int, error) {
+	since, err := s.store.LatestRecordDate(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error calling database: %w", err)
+	}
+
+	synced := 0
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return synced, err
+		}
+
+		resp, err := s.gw.GetExchangeRatePage(ctx, gateway.ExchangeRatePageRequest{
+			Since:      since,
+			PageNumber: page,
+			PageSize:   s.pageSize,
+		})
+		if err != nil {
+			return synced, fmt.Errorf("error calling gateway: %w", err)
+		}
+
+		if len(resp.Data) > 0 {
+			if err := s.store.Upsert(ctx, resp.Data); err != nil {
+				return synced, fmt.Errorf("error calling database: %w", err)
+			}
+			synced += len(resp.Data)
+		}
+
+		if page >= resp.Meta.TotalPages {
+			return synced, nil
+		}
+	}
+}
+
+// Run syncs the exchange rates immediately and then at every interval until the context is canceled.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		synced, err := s.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to sync exchange rates", slog.String("error", err.Error()))
+		} else if err == nil {
+			slog.Info("Exchange rates synced", slog.Int("records", synced), slog.Duration("duration", time.Since(start)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package ratesync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+)
+
+type stubGateway struct {
+	receivedInputs      []gateway.ExchangeRatePageRequest
+	getExchangeRatePage func(input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error)
+}
+
+func (s *stubGateway) GetExchangeRatePage(ctx context.Context, input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error) {
+	s.receivedInputs = append(s.receivedInputs, input)
+	return s.getExchangeRatePage(input)
+}
+
+type stubStore struct {
+	upserted         []gateway.CurrencyExchangeRate
+	upsert           func(ctx context.Context, rates []gateway.CurrencyExchangeRate) error
+	latestRecordDate func(ctx context.Context) (time.Time, error)
+}
+
+func (s *stubStore) Upsert(ctx context.Context, rates []gateway.CurrencyExchangeRate) error {
+	s.upserted = append(s.upserted, rates...)
+	return s.upsert(ctx, rates)
+}
+
+func (s *stubStore) LatestRecordDate(ctx context.Context) (time.Time, error) {
+	return s.latestRecordDate(ctx)
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	since := time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC)
+	pages := map[int][]gateway.CurrencyExchangeRate{
+		1: {
+			{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "4.8", RecordDate: "2023-06-30"},
+			{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.323", RecordDate: "2023-06-30"},
+		},
+		2: {
+			{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "5.003", RecordDate: "2023-09-30"},
+		},
+	}
+
+	mockGw := &stubGateway{
+		getExchangeRatePage: func(input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error) {
+			return &gateway.CurrencyExchangeRateResponse{
+				Data: pages[input.PageNumber],
+				Meta: gateway.ResponseMeta{TotalCount: 3, TotalPages: 2},
+			}, nil
+		},
+	}
+
+	mockStore := &stubStore{
+		upsert: func(ctx context.Context, rates []gateway.CurrencyExchangeRate) error {
+			return nil
+		},
+		latestRecordDate: func(ctx context.Context) (time.Time, error) {
+			return since, nil
+		},
+	}
+
+	syncer := NewSyncer(mockGw, mockStore)
+	got, gotErr := syncer.Sync(context.Background())
+	assert.NoError(t, gotErr)
+	assert.Equal(t, 3, got)
+
+	wantInputs := []gateway.ExchangeRatePageRequest{
+		{Since: since, PageNumber: 1, PageSize: gateway.DefaultPageSize},
+		{Since: since, PageNumber: 2, PageSize: gateway.DefaultPageSize},
+	}
+
+	assert.Equal(t, wantInputs, mockGw.receivedInputs)
+	assert.Equal(t, append(pages[1], pages[2]...), mockStore.upserted)
+}
+
+func TestSyncer_Sync_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mockGw    *stubGateway
+		mockStore *stubStore
+		wantErr   error
+	}{
+		"database error reading latest record date": {
+			mockGw: &stubGateway{},
+			mockStore: &stubStore{
+				latestRecordDate: func(ctx context.Context) (time.Time, error) {
+					return time.Time{}, someErr
+				},
+			},
+			wantErr: someErr,
+		},
+		"gateway error": {
+			mockGw: &stubGateway{
+				getExchangeRatePage: func(input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error) {
+					return nil, someErr
+				},
+			},
+			mockStore: &stubStore{
+				latestRecordDate: func(ctx context.Context) (time.Time, error) {
+					return time.Time{}, nil
+				},
+			},
+			wantErr: someErr,
+		},
+		"database error upserting": {
+			mockGw: &stubGateway{
+				getExchangeRatePage: func(input gateway.ExchangeRatePageRequest) (*gateway.CurrencyExchangeRateResponse, error) {
+					return &gateway.CurrencyExchangeRateResponse{
+						Data: []gateway.CurrencyExchangeRate{{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "4.8", RecordDate: "2023-06-30"}},
+						Meta: gateway.ResponseMeta{TotalCount: 1, TotalPages: 1},
+					}, nil
+				},
+			},
+			mockStore: &stubStore{
+				upsert: func(ctx context.Context, rates []gateway.CurrencyExchangeRate) error {
+					return someErr
+				},
+				latestRecordDate: func(ctx context.Context) (time.Time, error) {
+					return time.Time{}, nil
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			syncer := NewSyncer(tc.mockGw, tc.mockStore)
+			_, gotErr := syncer.Sync(context.Background())
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestSyncer_Sync_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockStore := &stubStore{
+		latestRecordDate: func(ctx context.Context) (time.Time, error) {
+			return time.Time{}, nil
+		},
+	}
+
+	syncer := NewSyncer(&stubGateway{}, mockStore)
+	got, gotErr := syncer.Sync(ctx)
+	assert.ErrorIs(t, gotErr, context.Canceled)
+	assert.Zero(t, got)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL dialect of the database a repository runs against. Queries are written with ? placeholders
+// and rebound to the placeholders of the dialect.
+type Dialect string
+
+// Supported dialects, named after the database drivers.
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// rebind rewrites the ? placeholders of a query, outside of string literals, into the placeholders of the dialect.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var (
+		b       strings.Builder
+		n       int
+		literal bool
+	)
+	b.Grow(len(query) + 16)
+
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			literal = !literal
+		case c == '?' && !literal:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// day returns the expression of the UTC calendar day of a timestamp column, as YYYY-MM-DD text.
+func (d Dialect) day(column string) string {
+	if d == Postgres {
+		return "to_char(" + column + " AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	}
+	return "substr(" + column + ", 1, 10)"
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := `SELECT id FROM transactions WHERE id = ? AND description LIKE ? ESCAPE '\' AND note = 'why?' LIMIT ?`
+
+	testCases := map[string]struct {
+		dialect Dialect
+		want    string
+	}{
+		"sqlite": {
+			dialect: SQLite,
+			want:    query,
+		},
+		"postgres": {
+			dialect: Postgres,
+			want:    `SELECT id FROM transactions WHERE id = $1 AND description LIKE $2 ESCAPE '\' AND note = 'why?' LIMIT $3`,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.dialect.rebind(query))
+		})
+	}
+}
+
+func TestDialect_Day(t *testing.T) {
+	assert.Equal(t, "substr(date, 1, 10)", SQLite.day("date"))
+	assert.Equal(t, "to_char(date AT TIME ZONE 'UTC', 'YYYY-MM-DD')", Postgres.day("date"))
+}
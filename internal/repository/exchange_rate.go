@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+const dateFormat = "2006-01-02"
+
+// ExchangeRateRepository handles database operations for the local copy of the exchange rates.
+type ExchangeRateRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	ops     operations
+}
+
+// NewExchangeRateRepository creates a new exchange rate repository with the provided database connection
+// of the given dialect, reporting its operations to observer unless it is nil.
+func NewExchangeRateRepository(db *sql.DB, dialect Dialect, observer QueryObserver) *ExchangeRateRepository {
+	return &ExchangeRateRepository{
+		db:      db,
+		dialect: dialect,
+		ops:     operations{observer: observer, repository: "exchange_rates", dialect: dialect},
+	}
+}
+
+// Upsert inserts the exchange rates into the database, replacing the rate of records that already exist.
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rates []gateway.CurrencyExchangeRate) (err error) {
+	ctx, end := r.ops.start(ctx, "upsert")
+	defer end(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.dialect.rebind(`
+		INSERT INTO exchange_rates 
+			(country_currency_desc, record_date, exchange_rate) 
+		VALUES 
+			(?, ?, ?) 
+		ON CONFLICT (country_currency_desc, record_date) 
+		DO UPDATE SET exchange_rate = excluded.exchange_rate`))
+	if err != nil {
+		return fmt.Errorf("failed to prepare exchange rate upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		recordDate, err := time.Parse(dateFormat, rate.RecordDate)
+		if err != nil {
+			return fmt.Errorf("invalid record date %q: %w", rate.RecordDate, err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, rate.CountryCurrencyDesc, recordDate, rate.ExchangeRate); err != nil {
+			return fmt.Errorf("failed to upsert exchange rate: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exchange rates: %w", err)
+	}
+
+	return nil
+}
+
+// LatestRecordDate returns the most recent record date stored, or the zero time if there are no exchange rates.
+func (r *ExchangeRateRepository) LatestRecordDate(ctx context.Context) (_ time.Time, err error) {
+	ctx, end := r.ops.start(ctx, "latest_record_date")
+	defer end(&err)
+
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(`
+		SELECT
+			record_date
+		FROM 
+			exchange_rates 
+		ORDER BY 
+			record_date DESC 
+		LIMIT 1`))
+
+	var recordDate time.Time
+	if err := row.Scan(&recordDate); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to retrieve latest record date: %w", err)
+	}
+
+	return recordDate, nil
+}
+
+// GetExchangeRate retrieves the stored exchange rates within the window of the transaction date
+// and returns the one selected by the rate policy of the request.
+func (r *ExchangeRateRepository) GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (_ *gateway.CurrencyExchangeRate, err error) {
+	ctx, end := r.ops.start(ctx, "get_exchange_rate")
+	defer end(&err)
+
+	from, to := input.Window()
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(`
+		SELECT
+			country_currency_desc, record_date, exchange_rate
+		FROM 
+			exchange_rates 
+		WHERE 
+			country_currency_desc = ? AND record_date >= ? AND record_date <= ? 
+		ORDER BY 
+			record_date DESC`),
+		input.CountryCurrencyDesc(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve exchange rate: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []gateway.CurrencyExchangeRate
+	for rows.Next() {
+		var (
+			rate       gateway.CurrencyExchangeRate
+			recordDate time.Time
+		)
+		if err := rows.Scan(&rate.CountryCurrencyDesc, &recordDate, &rate.ExchangeRate); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rate.RecordDate = recordDate.Format(dateFormat)
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve exchange rate: %w", err)
+	}
+
+	rate := input.Select(rates)
+	if rate == nil {
+		return nil, httpresponse.ErrNoCurrencyConversion
+	}
+
+	return rate, nil
+}
+
+// RateRanges returns the first and last record dates stored for each country currency.
+func (r *ExchangeRateRepository) RateRanges(ctx context.Context) (_ []currency.RateRange, err error) {
+	ctx, end := r.ops.start(ctx, "rate_ranges")
+	defer end(&err)
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(`
+		SELECT
+			country_currency_desc, MIN(record_date), MAX(record_date)
+		FROM
+			exchange_rates
+		GROUP BY
+			country_currency_desc
+		ORDER BY
+			country_currency_desc`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve exchange rate ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []currency.RateRange
+	for rows.Next() {
+		var (
+			rr       currency.RateRange
+			from, to string
+		)
+
+		if err := rows.Scan(&rr.CountryCurrencyDesc, &from, &to); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate range: %w", err)
+		}
+
+		if rr.From, err = parseStoredDate(from); err != nil {
+			return nil, err
+		}
+
+		if rr.To, err = parseStoredDate(to); err != nil {
+			return nil, err
+		}
+
+		ranges = append(ranges, rr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate exchange rate ranges: %w", err)
+	}
+
+	return ranges, nil
+}
+
+// parseStoredDate parses a record date returned by an aggregate, which drivers return as text
+// such as "2023-09-30 00:00:00+00:00" rather than as a time.
+func parseStoredDate(s string) (time.Time, error) {
+	if len(s) < len(dateFormat) {
+		return time.Time{}, fmt.Errorf("invalid stored record date %q", s)
+	}
+
+	date, err := time.Parse(dateFormat, s[:len(dateFormat)])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid stored record date %q", s)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+func TestExchangeRate_Upsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rates := []gateway.CurrencyExchangeRate{
+		{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "2023-09-30"},
+		{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "5.003", RecordDate: "2023-09-30"},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO exchange_rates (.+) ON CONFLICT`)
+	prep.ExpectExec().
+		WithArgs("Canada-Dollar", time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC), "1.353").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().
+		WithArgs("Brazil-Real", time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC), "5.003").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	repo := NewExchangeRateRepository(db, SQLite, nil)
+
+	gotErr := repo.Upsert(context.Background(), rates)
+	assert.NoError(t, gotErr)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeRate_Upsert_Error(t *testing.T) {
+	wantErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		rates   []gateway.CurrencyExchangeRate
+		mock    func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		"invalid record date": {
+			rates: []gateway.CurrencyExchangeRate{
+				{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "30/09/2023"},
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(`INSERT INTO exchange_rates`)
+				mock.ExpectRollback()
+			},
+			wantErr: "invalid record date",
+		},
+		"exec error": {
+			rates: []gateway.CurrencyExchangeRate{
+				{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "2023-09-30"},
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(`INSERT INTO exchange_rates`).ExpectExec().WillReturnError(wantErr)
+				mock.ExpectRollback()
+			},
+			wantErr: wantErr.Error(),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mock(mock)
+			repo := NewExchangeRateRepository(db, SQLite, nil)
+
+			gotErr := repo.Upsert(context.Background(), tc.rates)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExchangeRate_LatestRecordDate(t *testing.T) {
+	testCases := map[string]struct {
+		rows *sqlmock.Rows
+		want time.Time
+	}{
+		"stored exchange rates": {
+			rows: sqlmock.NewRows([]string{"record_date"}).AddRow(time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC)),
+			want: time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC),
+		},
+		"empty store": {
+			rows: sqlmock.NewRows([]string{"record_date"}),
+			want: time.Time{},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(`SELECT record_date FROM exchange_rates`).WillReturnRows(tc.rows)
+			repo := NewExchangeRateRepository(db, SQLite, nil)
+
+			got, gotErr := repo.LatestRecordDate(context.Background())
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExchangeRate_GetExchangeRate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	input := gateway.CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.October, 5, 12, 0, 0, 0, time.UTC),
+		Country:         "Canada",
+		Currency:        "Dollar",
+	}
+
+	rows := sqlmock.NewRows([]string{"country_currency_desc", "record_date", "exchange_rate"}).
+		AddRow("Canada-Dollar", time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC), "1.353")
+
+	mock.ExpectQuery(`SELECT country_currency_desc, record_date, exchange_rate FROM exchange_rates`).
+		WithArgs("Canada-Dollar", time.Date(2023, time.April, 5, 0, 0, 0, 0, time.UTC), time.Date(2023, time.October, 5, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(rows)
+
+	repo := NewExchangeRateRepository(db, SQLite, nil)
+
+	got, gotErr := repo.GetExchangeRate(context.Background(), input)
+	assert.NoError(t, gotErr)
+
+	want := &gateway.CurrencyExchangeRate{
+		CountryCurrencyDesc: "Canada-Dollar",
+		ExchangeRate:        "1.353",
+		RecordDate:          "2023-09-30",
+	}
+
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeRate_GetExchangeRate_Nearest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	input := gateway.CurrencyExchangeRateRequest{
+		TransactionDate: time.Date(2023, time.September, 5, 12, 0, 0, 0, time.UTC),
+		Country:         "Canada",
+		Currency:        "Dollar",
+		Policy:          gateway.PolicyNearest,
+	}
+
+	rows := sqlmock.NewRows([]string{"country_currency_desc", "record_date", "exchange_rate"}).
+		AddRow("Canada-Dollar", time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC), "1.325").
+		AddRow("Canada-Dollar", time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC), "1.353").
+		AddRow("Canada-Dollar", time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC), "1.320")
+
+	mock.ExpectQuery(`SELECT country_currency_desc, record_date, exchange_rate FROM exchange_rates`).
+		WithArgs("Canada-Dollar", time.Date(2023, time.March, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(rows)
+
+	repo := NewExchangeRateRepository(db, SQLite, nil)
+
+	got, gotErr := repo.GetExchangeRate(context.Background(), input)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, &gateway.CurrencyExchangeRate{
+		CountryCurrencyDesc: "Canada-Dollar",
+		ExchangeRate:        "1.353",
+		RecordDate:          "2023-09-30",
+	}, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeRate_GetExchangeRate_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mock    func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		"no rate within the window": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM exchange_rates`).
+					WillReturnRows(sqlmock.NewRows([]string{"country_currency_desc", "record_date", "exchange_rate"}))
+			},
+			wantErr: httpresponse.ErrNoCurrencyConversion.Error(),
+		},
+		"query error": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM exchange_rates`).WillReturnError(someErr)
+			},
+			wantErr: someErr.Error(),
+		},
+		"row error": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM exchange_rates`).
+					WillReturnRows(sqlmock.NewRows([]string{"country_currency_desc", "record_date", "exchange_rate"}).
+						AddRow("Canada-Dollar", time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC), "1.353").
+						RowError(0, someErr))
+			},
+			wantErr: someErr.Error(),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mock(mock)
+			repo := NewExchangeRateRepository(db, SQLite, nil)
+
+			got, gotErr := repo.GetExchangeRate(context.Background(), gateway.CurrencyExchangeRateRequest{
+				TransactionDate: time.Date(2023, time.October, 5, 0, 0, 0, 0, time.UTC),
+				Country:         "Canada",
+				Currency:        "Dollar",
+			})
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestExchangeRate_RateRanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"country_currency_desc", "from", "to"}).
+		AddRow("Brazil-Real", "2001-03-31 00:00:00+00:00", "2023-09-30 00:00:00+00:00").
+		AddRow("Canada-Dollar", "2001-03-31", "2023-06-30")
+
+	mock.ExpectQuery(`SELECT (.+) FROM exchange_rates GROUP BY country_currency_desc`).WillReturnRows(rows)
+
+	repo := NewExchangeRateRepository(db, SQLite, nil)
+
+	got, gotErr := repo.RateRanges(context.Background())
+	assert.NoError(t, gotErr)
+	assert.Equal(t, []currency.RateRange{
+		{
+			CountryCurrencyDesc: "Brazil-Real",
+			From:                time.Date(2001, time.March, 31, 0, 0, 0, 0, time.UTC),
+			To:                  time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			CountryCurrencyDesc: "Canada-Dollar",
+			From:                time.Date(2001, time.March, 31, 0, 0, 0, 0, time.UTC),
+			To:                  time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeRate_RateRanges_Error(t *testing.T) {
+	testCases := map[string]struct {
+		mock    func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		"query error": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM exchange_rates`).WillReturnError(errors.New("some error"))
+			},
+			wantErr: "failed to retrieve exchange rate ranges",
+		},
+		"invalid record date": {
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"country_currency_desc", "from", "to"}).
+					AddRow("Brazil-Real", "31/03/2001", "2023-09-30")
+				mock.ExpectQuery(`SELECT (.+) FROM exchange_rates`).WillReturnRows(rows)
+			},
+			wantErr: "invalid stored record date",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mock(mock)
+
+			repo := NewExchangeRateRepository(db, SQLite, nil)
+
+			got, gotErr := repo.RateRanges(context.Background())
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+			assert.Nil(t, got)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+)
+
+// reservationTimeout is how long a request may hold an idempotency key without completing it.
+// After that the request is assumed lost and another request with the same key may take over.
+const reservationTimeout = time.Minute
+
+// IdempotencyRepository handles database operations for idempotency keys.
+type IdempotencyRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	ops     operations
+	now     func() time.Time
+}
+
+// NewIdempotencyRepository creates a new idempotency repository with the provided database connection
+// of the given dialect, reporting its operations to observer unless it is nil.
+func NewIdempotencyRepository(db *sql.DB, dialect Dialect, observer QueryObserver) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:      db,
+		dialect: dialect,
+		ops:     operations{observer: observer, repository: "idempotency_keys", dialect: dialect},
+		now:     time.Now,
+	}
+}
+
+// Reserve claims the idempotency key for a request. It returns the reservation if the key was claimed,
+// or the existing record if the key was already used by a request that completed or is still in progress.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (_ *idempotency.Reservation, _ *idempotency.Record, err error) {
+	ctx, end := r.ops.start(ctx, "reserve")
+	defer end(&err)
+
+	// The reservation is later matched on its time, which must survive the microsecond precision of Postgres.
+	now := r.now().UTC().Truncate(time.Microsecond)
+
+	res, err := r.db.ExecContext(ctx, r.dialect.rebind(`
+		INSERT INTO idempotency_keys 
+			(idempotency_key, request_hash, created_at) 
+		VALUES 
+			(?, ?, ?) 
+		ON CONFLICT (idempotency_key) 
+		DO UPDATE SET request_hash = excluded.request_hash, created_at = excluded.created_at 
+		WHERE idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < ?`),
+		key, requestHash, now, now.Add(-reservationTimeout))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if reserved > 0 {
+		return &idempotency.Reservation{Key: key, RequestHash: requestHash, ReservedAt: now}, nil, nil
+	}
+
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(`
+		SELECT
+			idempotency_key, request_hash, status_code, response_body, created_at
+		FROM 
+			idempotency_keys 
+		WHERE 
+			idempotency_key = ?`),
+		key)
+
+	var (
+		rec        idempotency.Record
+		statusCode sql.NullInt64
+		body       sql.NullString
+	)
+	if err := row.Scan(&rec.Key, &rec.RequestHash, &statusCode, &body, &rec.CreatedAt); err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve idempotency key: %w", err)
+	}
+	rec.StatusCode = int(statusCode.Int64)
+	rec.ResponseBody = []byte(body.String)
+
+	return nil, &rec, nil
+}
+
+// Release frees an idempotency key whose request failed, so that the request can be retried with the same key.
+// It does nothing if the reservation timed out and was taken over by another request.
+func (r *IdempotencyRepository) Release(ctx context.Context, res idempotency.Reservation) (err error) {
+	ctx, end := r.ops.start(ctx, "release")
+	defer end(&err)
+
+	_, err = r.db.ExecContext(ctx, r.dialect.rebind(`
+		DELETE FROM 
+			idempotency_keys 
+		WHERE 
+			idempotency_key = ? AND request_hash = ? AND created_at = ? AND status_code IS NULL`),
+		res.Key, res.RequestHash, res.ReservedAt)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+)
+
+func TestIdempotency_Reserve(t *testing.T) {
+	now := time.Date(2023, time.September, 21, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		mock            func(mock sqlmock.Sqlmock)
+		wantReservation *idempotency.Reservation
+		want            *idempotency.Record
+	}{
+		"new key": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO idempotency_keys (.+) ON CONFLICT`).
+					WithArgs("key-1", "hash", now, now.Add(-reservationTimeout)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantReservation: &idempotency.Reservation{Key: "key-1", RequestHash: "hash", ReservedAt: now},
+		},
+		"completed key": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO idempotency_keys`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT idempotency_key, request_hash, status_code, response_body, created_at FROM idempotency_keys`).
+					WithArgs("key-1").
+					WillReturnRows(sqlmock.NewRows([]string{"idempotency_key", "request_hash", "status_code", "response_body", "created_at"}).
+						AddRow("key-1", "hash", 201, `{"id":"1"}`, now))
+			},
+			want: &idempotency.Record{
+				Key:          "key-1",
+				RequestHash:  "hash",
+				StatusCode:   201,
+				ResponseBody: []byte(`{"id":"1"}`),
+				CreatedAt:    now,
+			},
+		},
+		"key in progress": {
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO idempotency_keys`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT (.+) FROM idempotency_keys`).
+					WillReturnRows(sqlmock.NewRows([]string{"idempotency_key", "request_hash", "status_code", "response_body", "created_at"}).
+						AddRow("key-1", "hash", nil, nil, now))
+			},
+			want: &idempotency.Record{
+				Key:          "key-1",
+				RequestHash:  "hash",
+				ResponseBody: []byte{},
+				CreatedAt:    now,
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mock(mock)
+			repo := NewIdempotencyRepository(db, SQLite, nil)
+			repo.now = func() time.Time { return now }
+
+			gotReservation, got, gotErr := repo.Reserve(context.Background(), "key-1", "hash")
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantReservation, gotReservation)
+			assert.Equal(t, tc.want, got)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestIdempotency_Reserve_Error(t *testing.T) {
+	wantErr := errors.New("some error")
+
+	testCases := map[string]func(mock sqlmock.Sqlmock){
+		"insert error": func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec(`INSERT INTO idempotency_keys`).WillReturnError(wantErr)
+		},
+		"select error": func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec(`INSERT INTO idempotency_keys`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT (.+) FROM idempotency_keys`).WillReturnError(wantErr)
+		},
+	}
+
+	for title, setup := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			setup(mock)
+			repo := NewIdempotencyRepository(db, SQLite, nil)
+
+			gotReservation, got, gotErr := repo.Reserve(context.Background(), "key-1", "hash")
+			assert.Nil(t, gotReservation)
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, wantErr.Error())
+		})
+	}
+}
+
+func TestIdempotency_Release(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	reservedAt := time.Date(2023, time.September, 21, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE idempotency_key = ? AND request_hash = ? AND created_at = ? AND status_code IS NULL`).
+		WithArgs("key-1", "hash", reservedAt).
+		WillReturnError(sql.ErrConnDone)
+
+	repo := NewIdempotencyRepository(db, SQLite, nil)
+
+	gotErr := repo.Release(context.Background(), idempotency.Reservation{Key: "key-1", RequestHash: "hash", ReservedAt: reservedAt})
+	assert.ErrorIs(t, gotErr, sql.ErrConnDone)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
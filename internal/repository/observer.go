@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the tracer of the repository operations.
+const tracerName = "github.com/vickiliou/challenge-wex/internal/repository"
+
+// QueryObserver is notified of the duration and error of every operation of the repositories, e.g. to record metrics.
+// Expected outcomes, such as a transaction that does not exist, are reported without error.
+type QueryObserver interface {
+	ObserveQuery(repository, operation string, duration time.Duration, err error)
+}
+
+// operations traces the operations of a repository and reports them to its observer, if it has one.
+type operations struct {
+	observer   QueryObserver
+	repository string
+	dialect    Dialect
+}
+
+// start starts a span for an operation, in the returned context, and returns the function ending it and reporting
+// the operation with the error it returned through err. The function is meant to be deferred by a method with
+// a named error result.
+func (o operations) start(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	begin := time.Now()
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, o.repository+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(o.dbSystem(), semconv.DBSQLTable(o.repository), semconv.DBOperation(operation)),
+	)
+
+	return ctx, func(err *error) {
+		reported := *err
+		if errors.Is(reported, httpresponse.ErrNotFound) ||
+			errors.Is(reported, httpresponse.ErrPreconditionFailed) ||
+			errors.Is(reported, httpresponse.ErrNoCurrencyConversion) {
+			reported = nil
+		}
+
+		tracing.EndSpan(span, reported)
+
+		if o.observer != nil {
+			o.observer.ObserveQuery(o.repository, operation, time.Since(begin), reported)
+		}
+	}
+}
+
+// dbSystem returns the database system attribute of the dialect.
+func (o operations) dbSystem() attribute.KeyValue {
+	if o.dialect == Postgres {
+		return semconv.DBSystemPostgreSQL
+	}
+	return semconv.DBSystemSqlite
+}
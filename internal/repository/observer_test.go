@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type stubObserver struct {
+	repository string
+	operation  string
+	err        error
+	calls      int
+}
+
+func (s *stubObserver) ObserveQuery(repository, operation string, _ time.Duration, err error) {
+	s.repository, s.operation, s.err = repository, operation, err
+	s.calls++
+}
+
+func TestOperations_Start(t *testing.T) {
+	errDB := errors.New("database is locked")
+
+	testCases := map[string]struct {
+		err        error
+		wantErr    error
+		wantStatus codes.Code
+	}{
+		"success": {},
+		"error": {
+			err:        errDB,
+			wantErr:    errDB,
+			wantStatus: codes.Error,
+		},
+		"not found": {
+			err: fmt.Errorf("%w transaction ID 1", httpresponse.ErrNotFound),
+		},
+		"precondition failed": {
+			err: fmt.Errorf("%w: current version is 2", httpresponse.ErrPreconditionFailed),
+		},
+		"no exchange rate": {
+			err: httpresponse.ErrNoCurrencyConversion,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			prev := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+			observer := &stubObserver{}
+			ops := operations{observer: observer, repository: "transactions", dialect: SQLite}
+
+			ctx, end := ops.start(context.Background(), "create")
+			assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+			end(&tc.err)
+
+			assert.Equal(t, 1, observer.calls)
+			assert.Equal(t, "transactions", observer.repository)
+			assert.Equal(t, "create", observer.operation)
+			assert.Equal(t, tc.wantErr, observer.err)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "transactions.create", spans[0].Name())
+			assert.Equal(t, tc.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
+
+func TestOperations_Start_NoObserver(t *testing.T) {
+	err := errors.New("database is locked")
+
+	assert.NotPanics(t, func() {
+		_, end := operations{}.start(context.Background(), "create")
+		end(&err)
+	})
+}
@@ -5,31 +5,46 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
 	"github.com/vickiliou/challenge-wex/internal/transaction"
 )
 
 // Repository handles database operations for transactions.
+// Deleted transactions are kept with a deletion date and are only visible through their history.
 type Repository struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect Dialect
+	ops     operations
+	now     func() time.Time
 }
 
-// NewRepository creates a new repository with the provided database connection.
-func NewRepository(db *sql.DB) *Repository {
+// NewRepository creates a new repository with the provided database connection of the given dialect,
+// reporting its operations to observer unless it is nil.
+func NewRepository(db *sql.DB, dialect Dialect, observer QueryObserver) *Repository {
 	return &Repository{
-		db: db,
+		db:      db,
+		dialect: dialect,
+		ops:     operations{observer: observer, repository: "transactions", dialect: dialect},
+		now:     time.Now,
 	}
 }
 
 // Create inserts a transaction record into the database.
-func (r *Repository) Create(ctx context.Context, txn transaction.Transactions) (string, error) {
-	_, err := r.db.ExecContext(ctx, `
+func (r *Repository) Create(ctx context.Context, txn transaction.Transactions) (_ string, err error) {
+	ctx, end := r.ops.start(ctx, "create")
+	defer end(&err)
+
+	_, err = r.db.ExecContext(ctx, r.dialect.rebind(`
 		INSERT INTO transactions 
-			(id, description, date, amount) 
+			(id, description, date, amount, currency) 
 		VALUES 
-			(?, ?, ?, ?)`,
-		txn.ID, txn.Description, txn.TransactionDate, txn.Amount)
+			(?, ?, ?, ?, ?)`),
+		txn.ID, txn.Description, txn.TransactionDate, txn.Amount.MinorUnits(), txn.Amount.Currency())
 
 	if err != nil {
 		return "", fmt.Errorf("failed to create transaction: %w", err)
@@ -38,24 +53,497 @@ func (r *Repository) Create(ctx context.Context, txn transaction.Transactions) (
 	return txn.ID, nil
 }
 
+// CreateIdempotent inserts a transaction record and stores the response for its idempotency key in a single
+// database transaction, so that a transaction is never created without its response being replayable.
+// It fails, creating nothing, if the reservation timed out and was taken over, or the key was already completed.
+func (r *Repository) CreateIdempotent(ctx context.Context, txn transaction.Transactions, res idempotency.Response) (_ string, err error) {
+	ctx, end := r.ops.start(ctx, "create_idempotent")
+	defer end(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, r.dialect.rebind(`
+		INSERT INTO transactions 
+			(id, description, date, amount, currency) 
+		VALUES 
+			(?, ?, ?, ?, ?)`),
+		txn.ID, txn.Description, txn.TransactionDate, txn.Amount.MinorUnits(), txn.Amount.Currency())
+	if err != nil {
+		return "", fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, r.dialect.rebind(`
+		UPDATE 
+			idempotency_keys 
+		SET 
+			status_code = ?, response_body = ? 
+		WHERE 
+			idempotency_key = ? AND request_hash = ? AND created_at = ? AND status_code IS NULL`),
+		res.StatusCode, string(res.Body), res.Key, res.RequestHash, res.ReservedAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	completed, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	if completed == 0 {
+		return "", fmt.Errorf("failed to complete idempotency key: key %s is not reserved by the request", res.Key)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return txn.ID, nil
+}
+
+// CreateMany inserts transaction records in a single database transaction, so that either all or none are stored.
+func (r *Repository) CreateMany(ctx context.Context, txns []transaction.Transactions) (err error) {
+	ctx, end := r.ops.start(ctx, "create_many")
+	defer end(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.dialect.rebind(`
+		INSERT INTO transactions 
+			(id, description, date, amount, currency) 
+		VALUES 
+			(?, ?, ?, ?, ?)`))
+	if err != nil {
+		return fmt.Errorf("failed to prepare transaction insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, txn := range txns {
+		_, err := stmt.ExecContext(ctx, txn.ID, txn.Description, txn.TransactionDate, txn.Amount.MinorUnits(), txn.Amount.Currency())
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transactions: %w", err)
+	}
+
+	return nil
+}
+
 // FindByID retrieves a transaction record by its ID from the database.
-func (r *Repository) FindByID(ctx context.Context, id string) (*transaction.Transactions, error) {
-	row := r.db.QueryRowContext(ctx, `
+func (r *Repository) FindByID(ctx context.Context, id string) (_ *transaction.Transactions, err error) {
+	ctx, end := r.ops.start(ctx, "find_by_id")
+	defer end(&err)
+
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(`
 		SELECT
-			id, description, date, amount
+			id, description, date, amount, currency, version
 		FROM 
 			transactions 
 		WHERE 
-			id = ?`,
+			id = ? AND deleted_at IS NULL`),
 		id)
 
-	var txn transaction.Transactions
-	if err := row.Scan(&txn.ID, &txn.Description, &txn.TransactionDate, &txn.Amount); err != nil {
+	var (
+		txn      transaction.Transactions
+		amount   int64
+		currency string
+	)
+	if err := row.Scan(&txn.ID, &txn.Description, &txn.TransactionDate, &amount, &currency, &txn.Version); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%w transaction ID %s", httpresponse.ErrNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to retrieve transaction: %w", err)
 	}
+	txn.TransactionDate = txn.TransactionDate.UTC()
+	txn.Amount = money.New(amount, currency)
 
 	return &txn, nil
 }
+
+// Update replaces the fields of a transaction that is still at the version of txn, recording its prior version
+// in the history and incrementing its version.
+func (r *Repository) Update(ctx context.Context, txn transaction.Transactions, actor string) (err error) {
+	ctx, end := r.ops.start(ctx, "update")
+	defer end(&err)
+
+	return r.change(ctx, txn.ID, txn.Version, transaction.ChangeUpdate, actor, func(tx *sql.Tx, _ time.Time) error {
+		_, err := tx.ExecContext(ctx, r.dialect.rebind(`
+			UPDATE 
+				transactions 
+			SET 
+				description = ?, date = ?, amount = ?, currency = ?, version = version + 1 
+			WHERE 
+				id = ?`),
+			txn.Description, txn.TransactionDate, txn.Amount.MinorUnits(), txn.Amount.Currency(), txn.ID)
+		return err
+	})
+}
+
+// Delete soft deletes a transaction, recording its last version in the history.
+// A version other than zero is the version the transaction must still be at.
+func (r *Repository) Delete(ctx context.Context, id, actor string, version int) (err error) {
+	ctx, end := r.ops.start(ctx, "delete")
+	defer end(&err)
+
+	return r.change(ctx, id, version, transaction.ChangeDelete, actor, func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.ExecContext(ctx, r.dialect.rebind(`
+			UPDATE 
+				transactions 
+			SET 
+				deleted_at = ?, version = version + 1 
+			WHERE 
+				id = ?`),
+			now, id)
+		return err
+	})
+}
+
+// change copies the current version of a transaction that is not deleted, and is at the given version unless
+// it is zero, into the history, then applies the change within the same database transaction.
+func (r *Repository) change(ctx context.Context, id string, version int, kind, actor string, apply func(tx *sql.Tx, now time.Time) error) error {
+	now := r.now().UTC()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, r.dialect.rebind(`
+		INSERT INTO transaction_history 
+			(transaction_id, description, date, amount, currency, change, changed_by, changed_at) 
+		SELECT 
+			id, description, date, amount, currency, ?, ?, ? 
+		FROM 
+			transactions 
+		WHERE 
+			id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`),
+		kind, actor, now, id, version, version)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction history: %w", err)
+	}
+
+	recorded, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record transaction history: %w", err)
+	}
+
+	if recorded == 0 {
+		return r.unchanged(ctx, tx, id)
+	}
+
+	if err := apply(tx, now); err != nil {
+		return fmt.Errorf("failed to %s transaction: %w", kind, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction %s: %w", kind, err)
+	}
+
+	return nil
+}
+
+// unchanged returns why a transaction could not be changed: either it does not exist or is deleted,
+// or it is at another version than the expected one.
+func (r *Repository) unchanged(ctx context.Context, tx *sql.Tx, id string) error {
+	var current int
+	row := tx.QueryRowContext(ctx, r.dialect.rebind(`SELECT version FROM transactions WHERE id = ? AND deleted_at IS NULL`), id)
+	if err := row.Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w transaction ID %s", httpresponse.ErrNotFound, id)
+		}
+		return fmt.Errorf("failed to retrieve transaction: %w", err)
+	}
+
+	return fmt.Errorf("%w: current version is %d", httpresponse.ErrPreconditionFailed, current)
+}
+
+// History retrieves the prior versions of a transaction, oldest first, including those of a deleted transaction.
+func (r *Repository) History(ctx context.Context, id string) (_ []transaction.Revision, err error) {
+	ctx, end := r.ops.start(ctx, "history")
+	defer end(&err)
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(`
+		SELECT
+			description, date, amount, currency, change, changed_by, changed_at
+		FROM 
+			transaction_history 
+		WHERE 
+			transaction_id = ? 
+		ORDER BY 
+			id`),
+		id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve transaction history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []transaction.Revision
+	for rows.Next() {
+		var (
+			rev      transaction.Revision
+			amount   int64
+			currency string
+		)
+		if err := rows.Scan(&rev.Description, &rev.TransactionDate, &amount, &currency, &rev.Change, &rev.ChangedBy, &rev.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction history: %w", err)
+		}
+		rev.TransactionDate = rev.TransactionDate.UTC()
+		rev.ChangedAt = rev.ChangedAt.UTC()
+		rev.Amount = money.New(amount, currency)
+
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve transaction history: %w", err)
+	}
+
+	if len(revisions) > 0 {
+		return revisions, nil
+	}
+
+	var exists bool
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(`SELECT EXISTS (SELECT 1 FROM transactions WHERE id = ?)`), id)
+	if err := row.Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to retrieve transaction: %w", err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("%w transaction ID %s", httpresponse.ErrNotFound, id)
+	}
+
+	return revisions, nil
+}
+
+// findByIDsChunkSize is the number of IDs looked up per query, below the SQLite limit of bound parameters.
+const findByIDsChunkSize = 500
+
+// FindByIDs retrieves the transaction records with the given IDs. IDs that do not exist are skipped.
+func (r *Repository) FindByIDs(ctx context.Context, ids []string) (_ []transaction.Transactions, err error) {
+	ctx, end := r.ops.start(ctx, "find_by_ids")
+	defer end(&err)
+
+	txns := make([]transaction.Transactions, 0, len(ids))
+
+	for start := 0; start < len(ids); start += findByIDsChunkSize {
+		end := min(start+findByIDsChunkSize, len(ids))
+		chunk := ids[start:end]
+
+		args := make([]any, 0, len(chunk))
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+
+		query := fmt.Sprintf(`
+		SELECT
+			id, description, date, amount, currency, version
+		FROM 
+			transactions 
+		WHERE 
+			id IN (%s) AND deleted_at IS NULL`,
+			strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", "))
+
+		found, err := r.query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve transactions: %w", err)
+		}
+
+		txns = append(txns, found...)
+	}
+
+	return txns, nil
+}
+
+// List retrieves the transaction records matching the filter, ordered by the requested sort and then by ID.
+func (r *Repository) List(ctx context.Context, filter transaction.ListFilter) (_ []transaction.Transactions, err error) {
+	ctx, end := r.ops.start(ctx, "list")
+	defer end(&err)
+
+	query, args := buildListQuery(filter)
+
+	txns, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return txns, nil
+}
+
+// query runs a select statement returning transaction rows and scans them. Dates are returned in UTC, whatever the
+// time zone the driver returns them in.
+func (r *Repository) query(ctx context.Context, query string, args ...any) ([]transaction.Transactions, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txns []transaction.Transactions
+	for rows.Next() {
+		var (
+			txn      transaction.Transactions
+			amount   int64
+			currency string
+		)
+		if err := rows.Scan(&txn.ID, &txn.Description, &txn.TransactionDate, &amount, &currency, &txn.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		txn.TransactionDate = txn.TransactionDate.UTC()
+		txn.Amount = money.New(amount, currency)
+
+		txns = append(txns, txn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return txns, nil
+}
+
+// AmountsByDay retrieves how many transactions matching the filter have each amount on each calendar day,
+// ordered by day and amount. The sort, limit and cursor of the filter are ignored.
+func (r *Repository) AmountsByDay(ctx context.Context, filter transaction.ListFilter) (_ []transaction.DayAmount, err error) {
+	ctx, end := r.ops.start(ctx, "amounts_by_day")
+	defer end(&err)
+
+	conditions, args := listConditions(filter)
+
+	query := `
+		SELECT
+			` + r.dialect.day("date") + ` AS day, amount, currency, COUNT(*)
+		FROM 
+			transactions 
+		WHERE 
+			` + strings.Join(conditions, " AND ") + `
+		GROUP BY 
+			day, amount, currency 
+		ORDER BY 
+			day, amount`
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var amounts []transaction.DayAmount
+	for rows.Next() {
+		var (
+			a        transaction.DayAmount
+			day      string
+			amount   int64
+			currency string
+		)
+		if err := rows.Scan(&day, &amount, &currency, &a.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction amounts: %w", err)
+		}
+
+		if a.Day, err = time.Parse(dateFormat, day); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction day: %w", err)
+		}
+		a.Amount = money.New(amount, currency)
+
+		amounts = append(amounts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+
+	return amounts, nil
+}
+
+// buildListQuery builds the select statement and its arguments for the list filter.
+// Pagination is keyset based: the cursor excludes every row up to and including the last row of the previous page.
+func buildListQuery(filter transaction.ListFilter) (string, []any) {
+	conditions, args := listConditions(filter)
+
+	column := "date"
+	if filter.Sort.Field == transaction.SortByAmount {
+		column = "amount"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var last any = filter.After.TransactionDate
+		if filter.Sort.Field == transaction.SortByAmount {
+			last = filter.After.Amount
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparison))
+		args = append(args, last, last, filter.After.ID)
+	}
+
+	query := `
+		SELECT
+			id, description, date, amount, currency, version
+		FROM 
+			transactions 
+		WHERE 
+			` + strings.Join(conditions, " AND ")
+
+	query += fmt.Sprintf(`
+		ORDER BY 
+			%[1]s %[2]s, id %[2]s 
+		LIMIT ?`, column, direction)
+	args = append(args, filter.Limit)
+
+	return query, args
+}
+
+// listConditions returns the conditions and their arguments selecting the transactions that match the list filter,
+// apart from its cursor.
+func listConditions(filter transaction.ListFilter) ([]string, []any) {
+	var (
+		conditions = []string{"deleted_at IS NULL"}
+		args       []any
+	)
+
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, filter.DateFrom)
+	}
+
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, "date < ?")
+		args = append(args, filter.DateTo)
+	}
+
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "amount >= ?")
+		args = append(args, filter.MinAmount.MinorUnits())
+	}
+
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "amount <= ?")
+		args = append(args, filter.MaxAmount.MinorUnits())
+	}
+
+	if filter.Description != "" {
+		conditions = append(conditions, `LOWER(description) LIKE LOWER(?) ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.Description)+"%")
+	}
+
+	return conditions, args
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
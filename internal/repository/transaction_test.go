@@ -4,11 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
 	"github.com/vickiliou/challenge-wex/internal/transaction"
 )
 
@@ -21,14 +25,14 @@ func TestTransaction_Create(t *testing.T) {
 		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
-		Amount:          20.20,
+		Amount:          money.MustParse("20.20", money.USD),
 	}
 
-	mock.ExpectExec(`INSERT INTO transactions (id, description, date, amount)  VALUES (?, ?, ?, ?)`).
-		WithArgs(txn.ID, txn.Description, txn.TransactionDate, txn.Amount).
+	mock.ExpectExec(`INSERT INTO transactions (id, description, date, amount, currency)  VALUES (?, ?, ?, ?, ?)`).
+		WithArgs(txn.ID, txn.Description, txn.TransactionDate, int64(2020), money.USD).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	repo := NewRepository(db)
+	repo := NewRepository(db, SQLite, nil)
 
 	got, gotErr := repo.Create(context.Background(), txn)
 	assert.NoError(t, gotErr)
@@ -47,20 +51,181 @@ func TestTransaction_Create_Error(t *testing.T) {
 		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
-		Amount:          20.20,
+		Amount:          money.MustParse("20.20", money.USD),
 	}
 
 	mock.ExpectExec(`INSERT INTO transactions`).
-		WithArgs(txn.ID, txn.Description, txn.TransactionDate, txn.Amount).
+		WithArgs(txn.ID, txn.Description, txn.TransactionDate, int64(2020), money.USD).
 		WillReturnError(wantErr)
 
-	repo := NewRepository(db)
+	repo := NewRepository(db, SQLite, nil)
 
 	got, gotErr := repo.Create(context.Background(), txn)
 	assert.Empty(t, got)
 	assert.ErrorContains(t, gotErr, wantErr.Error())
 }
 
+func TestTransaction_CreateIdempotent(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	txn := transaction.Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.20", money.USD),
+	}
+	reservation := idempotency.Reservation{Key: "key-1", RequestHash: "hash", ReservedAt: time.Date(2023, time.September, 21, 12, 0, 0, 0, time.UTC)}
+	res := idempotency.Response{Reservation: reservation, StatusCode: 201, Body: []byte(`{"id":"b62a64c9-0008-4148-99f6-9c8086a1dd42"}`)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO transactions (id, description, date, amount, currency)  VALUES (?, ?, ?, ?, ?)`).
+		WithArgs(txn.ID, txn.Description, txn.TransactionDate, int64(2020), money.USD).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE idempotency_key = ? AND request_hash = ? AND created_at = ? AND status_code IS NULL`).
+		WithArgs(201, string(res.Body), "key-1", "hash", reservation.ReservedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.CreateIdempotent(context.Background(), txn, res)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, txn.ID, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_CreateIdempotent_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	txn := transaction.Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.20", money.USD),
+	}
+
+	testCases := map[string]struct {
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		"insert error": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transactions`).WillReturnError(someErr)
+				mock.ExpectRollback()
+			},
+			wantErr: someErr.Error(),
+		},
+		"key no longer reserved by the request": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transactions`).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`UPDATE idempotency_keys`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: "key key-1 is not reserved by the request",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.setup(mock)
+			repo := NewRepository(db, SQLite, nil)
+
+			got, gotErr := repo.CreateIdempotent(context.Background(), txn, idempotency.Response{Reservation: idempotency.Reservation{Key: "key-1"}, StatusCode: 201})
+			assert.Empty(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTransaction_CreateMany(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	txns := []transaction.Transactions{
+		{
+			ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.20", money.USD),
+		},
+		{
+			ID:              "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+			Description:     "rent",
+			TransactionDate: time.Date(2023, time.September, 22, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("1000", money.USD),
+		},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO transactions (id, description, date, amount, currency) VALUES (?, ?, ?, ?, ?)`)
+	for _, txn := range txns {
+		prep.ExpectExec().
+			WithArgs(txn.ID, txn.Description, txn.TransactionDate, txn.Amount.MinorUnits(), money.USD).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	repo := NewRepository(db, SQLite, nil)
+
+	gotErr := repo.CreateMany(context.Background(), txns)
+	assert.NoError(t, gotErr)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_CreateMany_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mockFunc func(mock sqlmock.Sqlmock)
+	}{
+		"begin error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(someErr)
+			},
+		},
+		"insert error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(`INSERT INTO transactions`).ExpectExec().WillReturnError(someErr)
+				mock.ExpectRollback()
+			},
+		},
+		"commit error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(`INSERT INTO transactions`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit().WillReturnError(someErr)
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repo := NewRepository(db, SQLite, nil)
+
+			gotErr := repo.CreateMany(context.Background(), []transaction.Transactions{{ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"}})
+			assert.ErrorIs(t, gotErr, someErr)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestTransaction_FindByID(t *testing.T) {
 	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 
@@ -72,17 +237,18 @@ func TestTransaction_FindByID(t *testing.T) {
 		ID:              id,
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-		Amount:          20.20,
+		Amount:          money.MustParse("20.20", money.USD),
+		Version:         2,
 	}
 
-	row := mock.NewRows([]string{"id", "description", "date", "amount"}).
-		AddRow(want.ID, want.Description, want.TransactionDate, want.Amount)
+	row := mock.NewRows([]string{"id", "description", "date", "amount", "currency", "version"}).
+		AddRow(want.ID, want.Description, want.TransactionDate, 2020, money.USD, 2)
 
-	mock.ExpectQuery(`SELECT id, description, date, amount FROM transactions WHERE id = ?`).
+	mock.ExpectQuery(`SELECT id, description, date, amount, currency, version FROM transactions WHERE id = ? AND deleted_at IS NULL`).
 		WithArgs(id).
 		WillReturnRows(row)
 
-	repo := NewRepository(db)
+	repo := NewRepository(db, SQLite, nil)
 
 	got, gotErr := repo.FindByID(context.Background(), id)
 	assert.NoError(t, gotErr)
@@ -103,7 +269,7 @@ func TestTransaction_FindByID_Error(t *testing.T) {
 	}{
 		"no rows error": {
 			rowErr:  sql.ErrNoRows,
-			rows:    mock.NewRows([]string{"description", "date", "amount"}),
+			rows:    mock.NewRows([]string{"description", "date", "amount", "currency"}),
 			wantErr: "not found",
 		},
 		"row scan error": {
@@ -119,7 +285,7 @@ func TestTransaction_FindByID_Error(t *testing.T) {
 				WillReturnRows(tc.rows).
 				WillReturnError(tc.rowErr)
 
-			repo := NewRepository(db)
+			repo := NewRepository(db, SQLite, nil)
 
 			got, gotErr := repo.FindByID(context.Background(), id)
 			assert.Nil(t, got)
@@ -127,3 +293,422 @@ func TestTransaction_FindByID_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestTransaction_List(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	want := []transaction.Transactions{
+		{
+			ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.20", money.USD),
+			Version:         2,
+		},
+	}
+
+	rows := mock.NewRows([]string{"id", "description", "date", "amount", "currency", "version"}).
+		AddRow(want[0].ID, want[0].Description, want[0].TransactionDate, 2020, money.USD, 2)
+
+	mock.ExpectQuery(`SELECT id, description, date, amount, currency, version FROM transactions WHERE deleted_at IS NULL ORDER BY date DESC, id DESC LIMIT ?`).
+		WithArgs(10).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.List(context.Background(), transaction.ListFilter{
+		Sort:  transaction.DefaultSort,
+		Limit: 10,
+	})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_List_Error(t *testing.T) {
+	wantErr := errors.New("some error")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM transactions`).WillReturnError(wantErr)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.List(context.Background(), transaction.ListFilter{Sort: transaction.DefaultSort, Limit: 10})
+	assert.Nil(t, got)
+	assert.ErrorContains(t, gotErr, wantErr.Error())
+}
+
+func TestTransaction_AmountsByDay(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	from := time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	want := []transaction.DayAmount{
+		{Day: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC), Amount: money.MustParse("20.20", money.USD), Count: 2},
+		{Day: time.Date(2023, time.September, 22, 0, 0, 0, 0, time.UTC), Amount: money.MustParse("5.00", money.USD), Count: 1},
+	}
+
+	rows := mock.NewRows([]string{"day", "amount", "currency", "count"}).
+		AddRow("2023-09-21", 2020, money.USD, 2).
+		AddRow("2023-09-22", 500, money.USD, 1)
+
+	mock.ExpectQuery(`SELECT substr(date, 1, 10) AS day, amount, currency, COUNT(*) FROM transactions WHERE deleted_at IS NULL AND date >= ? AND date < ? GROUP BY day, amount, currency ORDER BY day, amount`).
+		WithArgs(from, to).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.AmountsByDay(context.Background(), transaction.ListFilter{
+		DateFrom: from,
+		DateTo:   to,
+		Sort:     transaction.DefaultSort,
+		Limit:    10,
+	})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_AmountsByDay_Error(t *testing.T) {
+	testCases := map[string]struct {
+		rows    *sqlmock.Rows
+		err     error
+		wantErr string
+	}{
+		"query error": {
+			err:     errors.New("some error"),
+			wantErr: "failed to summarize transactions: some error",
+		},
+		"invalid day": {
+			rows:    sqlmock.NewRows([]string{"day", "amount", "currency", "count"}).AddRow("21/09/2023", 2020, money.USD, 1),
+			wantErr: "failed to parse transaction day",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			query := mock.ExpectQuery(`SELECT (.+) FROM transactions`)
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(tc.rows)
+			}
+
+			repo := NewRepository(db, SQLite, nil)
+
+			got, gotErr := repo.AmountsByDay(context.Background(), transaction.ListFilter{})
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestBuildListQuery(t *testing.T) {
+	date := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+	minAmount := money.MustParse("10", money.USD)
+	maxAmount := money.MustParse("100", money.USD)
+
+	testCases := map[string]struct {
+		filter        transaction.ListFilter
+		wantCondition string
+		wantOrder     string
+		wantArgs      []any
+	}{
+		"all filters sorted by date ascending": {
+			filter: transaction.ListFilter{
+				DateFrom:    date,
+				DateTo:      date.AddDate(0, 0, 1),
+				MinAmount:   &minAmount,
+				MaxAmount:   &maxAmount,
+				Description: "50%_off",
+				Sort:        transaction.Sort{Field: transaction.SortByDate},
+				Limit:       5,
+			},
+			wantCondition: `deleted_at IS NULL AND date >= ? AND date < ? AND amount >= ? AND amount <= ? AND LOWER(description) LIKE LOWER(?) ESCAPE '\'`,
+			wantOrder:     "ORDER BY date ASC, id ASC",
+			wantArgs:      []any{date, date.AddDate(0, 0, 1), int64(1000), int64(10000), `%50\%\_off%`, 5},
+		},
+		"cursor sorted by amount descending": {
+			filter: transaction.ListFilter{
+				Sort:  transaction.Sort{Field: transaction.SortByAmount, Descending: true},
+				Limit: 5,
+				After: &transaction.Cursor{Amount: 2020, ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+			},
+			wantCondition: "deleted_at IS NULL AND (amount < ? OR (amount = ? AND id < ?))",
+			wantOrder:     "ORDER BY amount DESC, id DESC",
+			wantArgs:      []any{int64(2020), int64(2020), "b62a64c9-0008-4148-99f6-9c8086a1dd42", 5},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotQuery, gotArgs := buildListQuery(tc.filter)
+			normalized := strings.Join(strings.Fields(gotQuery), " ")
+
+			assert.Contains(t, normalized, "WHERE "+tc.wantCondition)
+			assert.Contains(t, normalized, tc.wantOrder)
+			assert.Equal(t, tc.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestTransaction_FindByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ids := []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42", "f3a1c2d4-0008-4148-99f6-9c8086a1dd42"}
+	want := []transaction.Transactions{
+		{
+			ID:              ids[0],
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.20", money.USD),
+			Version:         2,
+		},
+	}
+
+	rows := mock.NewRows([]string{"id", "description", "date", "amount", "currency", "version"}).
+		AddRow(want[0].ID, want[0].Description, want[0].TransactionDate, 2020, money.USD, 2)
+
+	mock.ExpectQuery(`SELECT id, description, date, amount, currency, version FROM transactions WHERE id IN (?, ?) AND deleted_at IS NULL`).
+		WithArgs(ids[0], ids[1]).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.FindByIDs(context.Background(), ids)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_FindByIDs_Error(t *testing.T) {
+	wantErr := errors.New("some error")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM transactions WHERE id IN`).WillReturnError(wantErr)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.FindByIDs(context.Background(), []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42"})
+	assert.Nil(t, got)
+	assert.ErrorContains(t, gotErr, wantErr.Error())
+}
+
+func TestTransaction_Update(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2023, time.October, 2, 10, 0, 0, 0, time.UTC)
+	txn := transaction.Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "groceries",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("25.00", money.USD),
+		Version:         2,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO transaction_history (transaction_id, description, date, amount, currency, change, changed_by, changed_at) SELECT id, description, date, amount, currency, ?, ?, ? FROM transactions WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`).
+		WithArgs(transaction.ChangeUpdate, "jane", now, txn.ID, 2, 2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE transactions SET description = ?, date = ?, amount = ?, currency = ?, version = version + 1 WHERE id = ?`).
+		WithArgs(txn.Description, txn.TransactionDate, int64(2500), money.USD, txn.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db, SQLite, nil)
+	repo.now = func() time.Time { return now }
+
+	gotErr := repo.Update(context.Background(), txn, "jane")
+	assert.NoError(t, gotErr)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2023, time.October, 2, 10, 0, 0, 0, time.UTC)
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO transaction_history (transaction_id, description, date, amount, currency, change, changed_by, changed_at) SELECT id, description, date, amount, currency, ?, ?, ? FROM transactions WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`).
+		WithArgs(transaction.ChangeDelete, "jane", now, id, 0, 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE transactions SET deleted_at = ?, version = version + 1 WHERE id = ?`).
+		WithArgs(now, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db, SQLite, nil)
+	repo.now = func() time.Time { return now }
+
+	gotErr := repo.Delete(context.Background(), id, "jane", 0)
+	assert.NoError(t, gotErr)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_Delete_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		mockFunc func(mock sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		"begin error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(someErr)
+			},
+			wantErr: someErr,
+		},
+		"not found or already deleted": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transaction_history`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT version FROM transactions`).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: httpresponse.ErrNotFound,
+		},
+		"version mismatch": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transaction_history`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT version FROM transactions`).WillReturnRows(mock.NewRows([]string{"version"}).AddRow(3))
+				mock.ExpectRollback()
+			},
+			wantErr: httpresponse.ErrPreconditionFailed,
+		},
+		"history error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transaction_history`).WillReturnError(someErr)
+				mock.ExpectRollback()
+			},
+			wantErr: someErr,
+		},
+		"delete error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transaction_history`).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`UPDATE transactions`).WillReturnError(someErr)
+				mock.ExpectRollback()
+			},
+			wantErr: someErr,
+		},
+		"commit error": {
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO transaction_history`).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`UPDATE transactions`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(someErr)
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repo := NewRepository(db, SQLite, nil)
+
+			gotErr := repo.Delete(context.Background(), "b62a64c9-0008-4148-99f6-9c8086a1dd42", "jane", 2)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTransaction_History(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	want := []transaction.Revision{
+		{
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.20", money.USD),
+			Change:          transaction.ChangeUpdate,
+			ChangedBy:       "jane",
+			ChangedAt:       time.Date(2023, time.October, 2, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	rows := mock.NewRows([]string{"description", "date", "amount", "currency", "change", "changed_by", "changed_at"}).
+		AddRow(want[0].Description, want[0].TransactionDate, 2020, money.USD, want[0].Change, want[0].ChangedBy, want[0].ChangedAt)
+
+	mock.ExpectQuery(`SELECT description, date, amount, currency, change, changed_by, changed_at FROM transaction_history WHERE transaction_id = ? ORDER BY id`).
+		WithArgs(id).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db, SQLite, nil)
+
+	got, gotErr := repo.History(context.Background(), id)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_History_Empty(t *testing.T) {
+	testCases := map[string]struct {
+		exists  bool
+		wantErr error
+	}{
+		"never changed": {
+			exists: true,
+		},
+		"not found": {
+			wantErr: httpresponse.ErrNotFound,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			assert.NoError(t, err)
+			defer db.Close()
+
+			id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+			mock.ExpectQuery(`SELECT description, date, amount, currency, change, changed_by, changed_at FROM transaction_history WHERE transaction_id = ? ORDER BY id`).
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"description", "date", "amount", "currency", "change", "changed_by", "changed_at"}))
+			mock.ExpectQuery(`SELECT EXISTS (SELECT 1 FROM transactions WHERE id = ?)`).
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(tc.exists))
+
+			repo := NewRepository(db, SQLite, nil)
+
+			got, gotErr := repo.History(context.Background(), id)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Empty(t, got)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Allow())
+	b.Record(false)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.True(t, b.Allow())
+	b.Record(false)
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.True(t, b.Allow(), "probe call")
+	assert.False(t, b.Allow(), "only one probe at a time")
+
+	b.Record(false)
+	assert.Equal(t, StateOpen, b.State(), "failed probe reopens the circuit")
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, StateClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b := NewBreaker(2, time.Minute)
+
+	b.Allow()
+	b.Record(false)
+	b.Allow()
+	b.Record(true)
+	b.Allow()
+	b.Record(false)
+
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_Release(t *testing.T) {
+	now := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Allow()
+	b.Record(false)
+	now = now.Add(time.Minute)
+
+	assert.True(t, b.Allow())
+	b.Release()
+	assert.True(t, b.Allow(), "a released probe lets another probe through")
+}
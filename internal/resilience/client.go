@@ -0,0 +1,179 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"golang.org/x/exp/slog"
+)
+
+// ErrCircuitOpen indicates that a call was not made because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy represents how failed calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls made for a request, including the first one.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled on each further retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts. A Retry-After asking to wait longer ends the retries.
+	MaxDelay time.Duration
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is an HTTP client retrying failed calls with exponential backoff and jitter, and failing fast while
+// its circuit breaker is open. It is meant for idempotent requests without a body, such as the Treasury API GETs.
+type Client struct {
+	next    httpClient
+	policy  RetryPolicy
+	breaker *Breaker
+	jitter  func(d time.Duration) time.Duration
+	sleep   func(ctx context.Context, d time.Duration) error
+	now     func() time.Time
+}
+
+// NewClient creates a client wrapping next with the retry policy and the circuit breaker.
+func NewClient(next httpClient, policy RetryPolicy, breaker *Breaker) *Client {
+	return &Client{
+		next:    next,
+		policy:  policy,
+		breaker: breaker,
+		jitter:  fullJitter,
+		sleep:   sleep,
+		now:     time.Now,
+	}
+}
+
+// Do sends the request, retrying on network errors, 429 and 5xx responses. The last response or error is returned
+// once the attempts are exhausted. While the circuit breaker is open, it fails with httpresponse.ErrServiceUnavailable.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.Allow() {
+			return nil, fmt.Errorf("%w: %w", httpresponse.ErrServiceUnavailable, ErrCircuitOpen)
+		}
+
+		res, err := c.next.Do(req.Clone(ctx))
+		switch {
+		case err != nil && errors.Is(ctx.Err(), context.Canceled):
+			c.breaker.Release()
+			return nil, err
+		case err != nil:
+			c.breaker.Record(false)
+		default:
+			c.breaker.Record(res.StatusCode < http.StatusInternalServerError)
+		}
+
+		if !isRetryable(res, err) || attempt >= c.policy.MaxAttempts || ctx.Err() != nil || c.breaker.State() == StateOpen {
+			return res, err
+		}
+
+		delay := c.backoff(attempt)
+		if res != nil {
+			if after, ok := retryAfter(res.Header.Get("Retry-After"), c.now()); ok {
+				if after > c.policy.MaxDelay {
+					return res, nil
+				}
+				delay = max(delay, after)
+			}
+
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		slog.WarnContext(ctx, "Retrying request",
+			slog.String("url", req.URL.Redacted()),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("reason", failureReason(res, err)),
+		)
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the jittered delay before the retry following the given attempt.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.policy.BaseDelay
+	for i := 1; i < attempt && d < c.policy.MaxDelay; i++ {
+		d *= 2
+	}
+
+	return c.jitter(min(d, c.policy.MaxDelay))
+}
+
+// isRetryable reports whether a call failed in a way another attempt might not.
+func isRetryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// failureReason describes why a call failed, for logging.
+func failureReason(res *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return res.Status
+}
+
+// fullJitter returns a random duration between 0 and d.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+)
+
+type stubClient struct {
+	calls     int
+	responses []func() (*http.Response, error)
+}
+
+func (s *stubClient) Do(req *http.Request) (*http.Response, error) {
+	res := s.responses[min(s.calls, len(s.responses)-1)]
+	s.calls++
+	return res()
+}
+
+func respond(status int, header http.Header) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("body")),
+		}, nil
+	}
+}
+
+func fail(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return nil, err
+	}
+}
+
+func newTestClient(next httpClient, breaker *Breaker) (*Client, *[]time.Duration) {
+	var delays []time.Duration
+
+	c := NewClient(next, RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, breaker)
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	c.now = func() time.Time { return time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC) }
+
+	return c, &delays
+}
+
+func TestClient_Do(t *testing.T) {
+	testCases := map[string]struct {
+		responses  []func() (*http.Response, error)
+		wantStatus int
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		"success": {
+			responses:  []func() (*http.Response, error){respond(http.StatusOK, nil)},
+			wantStatus: http.StatusOK,
+			wantCalls:  1,
+		},
+		"client error is not retried": {
+			responses:  []func() (*http.Response, error){respond(http.StatusBadRequest, nil)},
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+		"server error then success": {
+			responses:  []func() (*http.Response, error){respond(http.StatusBadGateway, nil), fail(errors.New("connection reset")), respond(http.StatusOK, nil)},
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		"attempts exhausted": {
+			responses:  []func() (*http.Response, error){respond(http.StatusServiceUnavailable, nil)},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCalls:  3,
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		"retry after in seconds": {
+			responses:  []func() (*http.Response, error){respond(http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}}), respond(http.StatusOK, nil)},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+			wantDelays: []time.Duration{time.Second},
+		},
+		"retry after as a date": {
+			responses:  []func() (*http.Response, error){respond(http.StatusTooManyRequests, http.Header{"Retry-After": {"Thu, 21 Sep 2023 00:00:00 GMT"}}), respond(http.StatusOK, nil)},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+			wantDelays: []time.Duration{100 * time.Millisecond},
+		},
+		"retry after longer than the max delay": {
+			responses:  []func() (*http.Response, error){respond(http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}})},
+			wantStatus: http.StatusTooManyRequests,
+			wantCalls:  1,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			next := &stubClient{responses: tc.responses}
+			c, delays := newTestClient(next, NewBreaker(10, time.Minute))
+
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost/rates", nil)
+			got, gotErr := c.Do(req)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantStatus, got.StatusCode)
+			assert.Equal(t, tc.wantCalls, next.calls)
+			assert.Equal(t, tc.wantDelays, *delays)
+		})
+	}
+}
+
+func TestClient_Do_CircuitOpen(t *testing.T) {
+	next := &stubClient{responses: []func() (*http.Response, error){fail(errors.New("connection refused"))}}
+	c, _ := newTestClient(next, NewBreaker(2, time.Minute))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/rates", nil)
+
+	_, gotErr := c.Do(req)
+	assert.ErrorContains(t, gotErr, "connection refused")
+	assert.Equal(t, 2, next.calls, "retries stop once the breaker opens")
+
+	_, gotErr = c.Do(req)
+	assert.ErrorIs(t, gotErr, ErrCircuitOpen)
+	assert.ErrorIs(t, gotErr, httpresponse.ErrServiceUnavailable)
+	assert.Equal(t, 2, next.calls, "no call is made while the circuit is open")
+}
+
+func TestClient_Do_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	next := &stubClient{responses: []func() (*http.Response, error){func() (*http.Response, error) {
+		cancel()
+		return nil, context.Canceled
+	}}}
+	breaker := NewBreaker(1, time.Minute)
+	c, _ := newTestClient(next, breaker)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/rates", nil)
+
+	_, gotErr := c.Do(req)
+	assert.ErrorIs(t, gotErr, context.Canceled)
+	assert.Equal(t, 1, next.calls)
+	assert.Equal(t, StateClosed, breaker.State(), "a cancelled call is not an upstream failure")
+}
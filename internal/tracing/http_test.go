@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type stubRoundTripper struct {
+	roundTrip func(req *http.Request) (*http.Response, error)
+}
+
+func (s *stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return s.roundTrip(req)
+}
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceparent   = "00-" + parentTraceID + "-00f067aa0ba902b7-01"
+)
+
+func TestMiddleware(t *testing.T) {
+	testCases := map[string]struct {
+		path       string
+		header     http.Header
+		wantName   string
+		wantStatus codes.Code
+		wantTrace  string
+	}{
+		"route pattern": {
+			path:       "/v1/transactions/f47ac10b-58cc-4372-a567-0e02b2c3d479",
+			wantName:   "GET /v1/transactions/{id}",
+			wantStatus: codes.Unset,
+		},
+		"server error": {
+			path:       "/fail",
+			wantName:   "GET /fail",
+			wantStatus: codes.Error,
+		},
+		"unmatched route": {
+			path:       "/unknown",
+			wantName:   "GET",
+			wantStatus: codes.Unset,
+		},
+		"caller trace": {
+			path:       "/v1/transactions/f47ac10b-58cc-4372-a567-0e02b2c3d479",
+			header:     http.Header{"Traceparent": {traceparent}},
+			wantName:   "GET /v1/transactions/{id}",
+			wantStatus: codes.Unset,
+			wantTrace:  parentTraceID,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			recorder := recordSpans(t)
+
+			var handlerSpan trace.SpanContext
+			r := chi.NewRouter()
+			r.Use(Middleware)
+			r.Get("/v1/transactions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+			})
+			r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tc.wantName, spans[0].Name())
+			assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+			assert.Equal(t, tc.wantStatus, spans[0].Status().Code)
+			if tc.wantTrace != "" {
+				assert.Equal(t, tc.wantTrace, spans[0].SpanContext().TraceID().String())
+			}
+			if handlerSpan.IsValid() {
+				assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID())
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+
+	testCases := map[string]struct {
+		res        *http.Response
+		err        error
+		wantStatus codes.Code
+	}{
+		"success": {
+			res:        &http.Response{StatusCode: http.StatusOK},
+			wantStatus: codes.Unset,
+		},
+		"error status": {
+			res:        &http.Response{StatusCode: http.StatusServiceUnavailable},
+			wantStatus: codes.Error,
+		},
+		"network error": {
+			err:        errNetwork,
+			wantStatus: codes.Error,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			recorder := recordSpans(t)
+
+			var sent *http.Request
+			transport := NewTransport(&stubRoundTripper{
+				roundTrip: func(req *http.Request) (*http.Response, error) {
+					sent = req
+					return tc.res, tc.err
+				},
+			})
+
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "Gateway.GetExchangeRate")
+			req := httptest.NewRequest(http.MethodGet, "https://api.fiscaldata.treasury.gov/services/api", nil).WithContext(ctx)
+			res, err := transport.RoundTrip(req)
+			parent.End()
+
+			assert.Equal(t, tc.res, res)
+			assert.Equal(t, tc.err, err)
+			assert.Empty(t, req.Header.Get("Traceparent"))
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			client := spans[0]
+			assert.Equal(t, trace.SpanKindClient, client.SpanKind())
+			assert.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID())
+			assert.Equal(t, tc.wantStatus, client.Status().Code)
+			assert.Contains(t, client.Attributes(), attribute.String("server.address", "api.fiscaldata.treasury.gov"))
+
+			sc := client.SpanContext()
+			assert.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", sent.Header.Get("Traceparent"))
+		})
+	}
+}
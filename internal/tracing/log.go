@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// LogHandler is a slog handler adding the trace_id and span_id of the span in the context of a record to the record,
+// so that the logs of a request can be found from its trace and conversely.
+type LogHandler struct {
+	next slog.Handler
+}
+
+// NewLogHandler creates a handler passing the records to next.
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{next: next}
+}
+
+// Enabled reports whether next handles records at the given level.
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the trace and span IDs to the record, if its context holds a span, and passes it to next.
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler passing the records to next with the given attributes.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler passing the records to next with the given group.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{next: h.next.WithGroup(name)}
+}
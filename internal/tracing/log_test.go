@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+)
+
+func TestLogHandler(t *testing.T) {
+	recordSpans(t)
+	ctx, span := otel.Tracer("test").Start(context.Background(), "Service.Get")
+	defer span.End()
+
+	testCases := map[string]struct {
+		ctx       context.Context
+		wantTrace bool
+	}{
+		"span": {
+			ctx:       ctx,
+			wantTrace: true,
+		},
+		"no span": {
+			ctx: context.Background(),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(NewLogHandler(slog.NewJSONHandler(&out, nil))).With("request", "retrieve")
+
+			logger.InfoContext(tc.ctx, "Transaction retrieved successfully")
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+			assert.Equal(t, "retrieve", got["request"])
+			if tc.wantTrace {
+				assert.Equal(t, span.SpanContext().TraceID().String(), got["trace_id"])
+				assert.Equal(t, span.SpanContext().SpanID().String(), got["span_id"])
+			} else {
+				assert.NotContains(t, got, "trace_id")
+				assert.NotContains(t, got, "span_id")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the spans.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// tracerName is the name of the tracer of the spans started by this package.
+const tracerName = "github.com/vickiliou/challenge-wex/internal/tracing"
+
+// Options represents how spans are sampled and exported.
+type Options struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterOTLP.
+	Exporter string
+
+	// Endpoint is the URL the OTLP exporter sends spans to over HTTP, e.g. http://localhost:4318/v1/traces.
+	// If empty, it is read from the OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables.
+	Endpoint string
+
+	// ServiceName is the name of the service the spans are attributed to.
+	ServiceName string
+
+	// SampleRatio is the fraction of the traces started by the service that are sampled.
+	// Traces started by a caller follow the sampling decision of the caller.
+	SampleRatio float64
+}
+
+// Setup sets the W3C trace context and baggage propagator and, unless the exporter is ExporterNone, the tracer provider
+// exporting the spans, to stdout for ExporterStdout. It returns the function flushing the pending spans and stopping
+// the export, to be called on shutdown. Without exporter, the trace context of incoming requests is still propagated.
+func Setup(ctx context.Context, opts Options, stdout io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// EndSpan ends a span, recording err as its error if it is set and does not match any of the expected errors,
+// such as a validation error, which are outcomes of the operation rather than failures.
+func EndSpan(span trace.Span, err error, expected ...error) {
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			err = nil
+			break
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
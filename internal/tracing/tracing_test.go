@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans sets a tracer provider recording the spans, and the W3C trace context propagator,
+// for the duration of a test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, ServiceName: "wex", SampleRatio: 1}, &out)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "Service.Get")
+	span.End()
+
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"Name":"Service.Get"`)
+	assert.Contains(t, out.String(), `{"Key":"service.name","Value":{"Type":"STRING","Value":"wex"}}`)
+}
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterNone}, nil)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+}
+
+func TestSetup_UnsupportedExporter(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{Exporter: "jaeger"}, nil)
+	assert.ErrorContains(t, err, `unsupported trace exporter "jaeger"`)
+	assert.Nil(t, shutdown)
+}
+
+func TestEndSpan(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "ok")
+	EndSpan(span, nil)
+	_, span = otel.Tracer("test").Start(context.Background(), "failed")
+	EndSpan(span, assert.AnError)
+	_, span = otel.Tracer("test").Start(context.Background(), "expected")
+	EndSpan(span, fmt.Errorf("wrapped: %w", assert.AnError), errors.New("other"), assert.AnError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, assert.AnError.Error(), spans[1].Status().Description)
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+}
@@ -0,0 +1,110 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxConversionItems is the largest number of transactions converted in a single request.
+const MaxConversionItems = 10000
+
+// ConversionRequest represents a request to convert many transactions to a target currency.
+// The transactions are selected either by ID or by filter.
+// RatePolicy and LookbackMonths override the default rate selection when set.
+type ConversionRequest struct {
+	IDs            []string          `json:"ids"`
+	Filter         *ConversionFilter `json:"filter"`
+	Country        string            `json:"country"`
+	Currency       string            `json:"currency"`
+	RatePolicy     string            `json:"rate_policy"`
+	LookbackMonths int               `json:"lookback_months"`
+}
+
+// ConversionFilter represents the criteria used to select the transactions to convert.
+type ConversionFilter struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	MinAmount   string `json:"min_amount"`
+	MaxAmount   string `json:"max_amount"`
+	Description string `json:"description"`
+}
+
+// ConversionResponse represents the result of every transaction of a conversion request.
+type ConversionResponse struct {
+	Data []ConversionResult `json:"data"`
+}
+
+// ConversionResult represents the converted transaction or the reason it could not be converted.
+type ConversionResult struct {
+	ID     string            `json:"id"`
+	Result *RetrieveResponse `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// validate checks if the conversion request data is valid.
+func (r *ConversionRequest) validate() error {
+	if len(r.IDs) == 0 && r.Filter == nil {
+		return errors.New("ids or filter is required")
+	}
+
+	if len(r.IDs) > 0 && r.Filter != nil {
+		return errors.New("ids and filter must not be used together")
+	}
+
+	if len(r.IDs) > MaxConversionItems {
+		return fmt.Errorf("ids must not exceed %d items", MaxConversionItems)
+	}
+
+	if isEmpty(r.Currency) {
+		return errors.New("currency is required")
+	}
+
+	return nil
+}
+
+// listFilter converts the conversion filter into the repository filter, sorted by transaction date.
+func (f *ConversionFilter) listFilter() (ListFilter, error) {
+	input := ListRequest{
+		From:        f.From,
+		To:          f.To,
+		MinAmount:   f.MinAmount,
+		MaxAmount:   f.MaxAmount,
+		Description: f.Description,
+	}
+
+	filter, err := input.filter()
+	if err != nil {
+		return ListFilter{}, err
+	}
+	filter.Limit = MaxConversionItems + 1
+
+	return filter, nil
+}
+
+// transactionDay returns the calendar day of the transaction, which is what exchange rates are selected by.
+func transactionDay(txn Transactions) time.Time {
+	y, m, d := txn.TransactionDate.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// distinctDays returns the distinct calendar days of the transactions, most recent first.
+func distinctDays(txns []Transactions) []time.Time {
+	seen := make(map[time.Time]bool, len(txns))
+	days := make([]time.Time, 0, len(txns))
+
+	for _, txn := range txns {
+		day := transactionDay(txn)
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].After(days[j])
+	})
+
+	return days
+}
@@ -0,0 +1,78 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction_ConversionRequest_Validate(t *testing.T) {
+	testCases := map[string]*ConversionRequest{
+		"by ids": {
+			IDs:      []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+			Country:  "Canada",
+			Currency: "Dollar",
+		},
+		"by filter": {
+			Filter:   &ConversionFilter{From: "2023-09-01"},
+			Country:  "Canada",
+			Currency: "Dollar",
+		},
+	}
+
+	for title, input := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotErr := input.validate()
+			assert.Nil(t, gotErr)
+		})
+	}
+}
+
+func TestTransaction_ConversionRequest_Validate_Error(t *testing.T) {
+	ids := []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42"}
+
+	testCases := map[string]struct {
+		input   *ConversionRequest
+		wantErr string
+	}{
+		"no ids nor filter": {
+			input:   &ConversionRequest{Country: "Canada", Currency: "Dollar"},
+			wantErr: "ids or filter is required",
+		},
+		"ids and filter": {
+			input:   &ConversionRequest{IDs: ids, Filter: &ConversionFilter{}, Country: "Canada", Currency: "Dollar"},
+			wantErr: "must not be used together",
+		},
+		"too many ids": {
+			input:   &ConversionRequest{IDs: make([]string, MaxConversionItems+1), Country: "Canada", Currency: "Dollar"},
+			wantErr: "must not exceed",
+		},
+		"empty currency": {
+			input:   &ConversionRequest{IDs: ids, Country: "Canada"},
+			wantErr: "required",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotErr := tc.input.validate()
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestDistinctDays(t *testing.T) {
+	txns := []Transactions{
+		{TransactionDate: time.Date(2023, time.September, 1, 10, 0, 0, 0, time.UTC)},
+		{TransactionDate: time.Date(2023, time.September, 21, 8, 0, 0, 0, time.UTC)},
+		{TransactionDate: time.Date(2023, time.September, 1, 23, 0, 0, 0, time.UTC)},
+	}
+
+	want := []time.Time{
+		time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, want, distinctDays(txns))
+}
@@ -0,0 +1,195 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+// exportPageSize is the number of transactions read from the repository at a time while exporting.
+const exportPageSize = 1000
+
+// ExportHeader is the header of the CSV export, naming the fields of ExportRow.Record.
+var ExportHeader = []string{
+	"id",
+	"description",
+	"transaction_date",
+	"original_amount",
+	"exchange_rate",
+	"exchange_rate_date",
+	"converted_amount",
+	"country_currency_desc",
+	"exchange_rate_provider",
+	"error",
+}
+
+// ExportRow represents an exported transaction along with its conversion, or the reason it could not be converted.
+type ExportRow struct {
+	ID                  string       `json:"id"`
+	Description         string       `json:"description"`
+	TransactionDate     time.Time    `json:"transaction_date"`
+	OriginalAmount      money.Money  `json:"original_amount"`
+	ExchangeRate        *money.Rate  `json:"exchange_rate,omitempty"`
+	RateDate            string       `json:"exchange_rate_date,omitempty"`
+	ConvertedAmount     *money.Money `json:"converted_amount,omitempty"`
+	CountryCurrencyDesc string       `json:"country_currency_desc,omitempty"`
+	RateProvider        string       `json:"exchange_rate_provider,omitempty"`
+	Error               string       `json:"error,omitempty"`
+}
+
+// Record returns the fields of the row in the order of ExportHeader.
+func (r ExportRow) Record() []string {
+	var rate, converted string
+	if r.ExchangeRate != nil {
+		rate = r.ExchangeRate.String()
+	}
+	if r.ConvertedAmount != nil {
+		converted = r.ConvertedAmount.String()
+	}
+
+	return []string{
+		r.ID,
+		r.Description,
+		r.TransactionDate.Format(time.RFC3339),
+		r.OriginalAmount.String(),
+		rate,
+		r.RateDate,
+		converted,
+		r.CountryCurrencyDesc,
+		r.RateProvider,
+		r.Error,
+	}
+}
+
+// ExportRequest represents a request to export the transactions matching a filter, converted to a target currency.
+// Country, Currency, RatePolicy and LookbackMonths are as in RetrieveRequest.
+type ExportRequest struct {
+	Filter         ConversionFilter
+	Country        string
+	Currency       string
+	RatePolicy     string
+	LookbackMonths string
+}
+
+// validate checks if the export request data is valid.
+func (r *ExportRequest) validate() error {
+	if isEmpty(r.Currency) {
+		return errors.New("currency is required")
+	}
+
+	return nil
+}
+
+// listFilter converts the filter of the export request into the repository filter of its first page,
+// sorted by transaction date from the oldest.
+func (r *ExportRequest) listFilter() (ListFilter, error) {
+	filter, err := r.Filter.listFilter()
+	if err != nil {
+		return ListFilter{}, err
+	}
+
+	filter.Sort = Sort{Field: SortByDate}
+	filter.Limit = exportPageSize
+
+	return filter, nil
+}
+
+// Export converts every transaction matching the filter of the request to the target currency and passes the results
+// to write, one at a time and ordered by transaction date, so that any number of transactions can be exported.
+// The request is validated before write is first called; an error returned by write stops the export.
+// As in ConvertBatch, a transaction that cannot be converted is reported with an error.
+func (s *Service) Export(ctx context.Context, input ExportRequest, write func(ExportRow) error) (err error) {
+	ctx, span := startSpan(ctx, "Export")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.validate(); err != nil {
+		return fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	target, err := currency.Resolve(input.Country, input.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	lookbackMonths := 0
+	if !isEmpty(input.LookbackMonths) {
+		if lookbackMonths, err = strconv.Atoi(input.LookbackMonths); err != nil {
+			return fmt.Errorf("%w: lookback_months must be an integer", httpresponse.ErrValidation)
+		}
+	}
+
+	selection, err := s.rateSelection.override(input.RatePolicy, lookbackMonths)
+	if err != nil {
+		return fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	filter, err := input.listFilter()
+	if err != nil {
+		return fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	rates := make(map[time.Time]dayExchangeRate)
+
+	for {
+		txns, err := s.repo.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("error calling database: %w", err)
+		}
+
+		for _, txn := range txns {
+			day := transactionDay(txn)
+
+			rate, ok := rates[day]
+			if !ok {
+				rate.rate, rate.err = s.getExchangeRate(ctx, exchangeRateRequest(day, target, selection))
+				rates[day] = rate
+			}
+
+			if err := write(exportRow(txn, rate, currencyCode(target))); err != nil {
+				return err
+			}
+		}
+
+		if len(txns) < filter.Limit {
+			return nil
+		}
+
+		after := newCursor(filter.Sort, txns[len(txns)-1])
+		filter.After = &after
+	}
+}
+
+// exportRow converts the transaction with the exchange rate of its day into an export row.
+func exportRow(txn Transactions, rate dayExchangeRate, currency string) ExportRow {
+	row := ExportRow{
+		ID:              txn.ID,
+		Description:     txn.Description,
+		TransactionDate: txn.TransactionDate,
+		OriginalAmount:  txn.Amount,
+	}
+
+	if rate.err != nil {
+		row.Error = rate.err.Error()
+		return row
+	}
+
+	res, err := convert(txn, rate.rate, currency)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+
+	row.ExchangeRate = &res.ExchangeRate
+	row.RateDate = res.RateDate
+	row.ConvertedAmount = &res.ConvertedAmount
+	row.CountryCurrencyDesc = res.CountryCurrencyDesc
+	row.RateProvider = res.RateProvider
+
+	return row
+}
@@ -0,0 +1,198 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestService_Export(t *testing.T) {
+	first := time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+	txns := make([]Transactions, 0, exportPageSize+1)
+	for i := 0; i <= exportPageSize; i++ {
+		txns = append(txns, Transactions{
+			ID:              fmt.Sprintf("b62a64c9-0008-4148-99f6-%012d", i),
+			Description:     "food",
+			TransactionDate: first.AddDate(0, 0, i%2),
+			Amount:          money.MustParse("10", money.USD),
+		})
+	}
+
+	var filters []ListFilter
+	mockRepo := &stubRepository{
+		list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+			filters = append(filters, filter)
+			if filter.After == nil {
+				return txns[:exportPageSize], nil
+			}
+			return txns[exportPageSize:], nil
+		},
+	}
+
+	mockGw := &stubProvider{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			if input.TransactionDate.Equal(first) {
+				return nil, httpresponse.ErrNoCurrencyConversion
+			}
+			return &gateway.CurrencyExchangeRate{
+				CountryCurrencyDesc: "Canada-Dollar",
+				ExchangeRate:        "1.35",
+				RecordDate:          "2023-06-30",
+				Provider:            "treasury",
+			}, nil
+		},
+	}
+
+	var got []ExportRow
+	svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+	gotErr := svc.Export(context.Background(), ExportRequest{
+		Filter:   ConversionFilter{From: "2023-09-01"},
+		Currency: "CAD",
+	}, func(row ExportRow) error {
+		got = append(got, row)
+		return nil
+	})
+	assert.NoError(t, gotErr)
+
+	rate := money.MustParseRate("1.35")
+	converted := money.MustParse("13.50", "CAD")
+
+	assert.Len(t, got, exportPageSize+1)
+	assert.Equal(t, ExportRow{
+		ID:              txns[0].ID,
+		Description:     "food",
+		TransactionDate: first,
+		OriginalAmount:  txns[0].Amount,
+		Error:           "error calling exchange rate provider: " + httpresponse.ErrNoCurrencyConversion.Error(),
+	}, got[0])
+	assert.Equal(t, ExportRow{
+		ID:                  txns[1].ID,
+		Description:         "food",
+		TransactionDate:     first.AddDate(0, 0, 1),
+		OriginalAmount:      txns[1].Amount,
+		ExchangeRate:        &rate,
+		RateDate:            "2023-06-30",
+		ConvertedAmount:     &converted,
+		CountryCurrencyDesc: "Canada-Dollar",
+		RateProvider:        "treasury",
+	}, got[1])
+	assert.Equal(t, txns[exportPageSize].ID, got[exportPageSize].ID)
+
+	assert.Len(t, filters, 2)
+	assert.Equal(t, first, filters[0].DateFrom)
+	assert.Equal(t, Sort{Field: SortByDate}, filters[0].Sort)
+	assert.Equal(t, exportPageSize, filters[0].Limit)
+	assert.Equal(t, &Cursor{
+		Sort:            "transaction_date",
+		TransactionDate: txns[exportPageSize-1].TransactionDate,
+		Amount:          1000,
+		ID:              txns[exportPageSize-1].ID,
+	}, filters[1].After)
+	assert.Len(t, mockGw.receivedInputs, 2)
+}
+
+func TestService_Export_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    ExportRequest
+		mockRepo *stubRepository
+		write    func(row ExportRow) error
+		wantErr  error
+	}{
+		"missing currency": {
+			input:    ExportRequest{},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"unknown currency": {
+			input:    ExportRequest{Currency: "XYZ"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid lookback": {
+			input:    ExportRequest{Currency: "CAD", LookbackMonths: "six"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid filter": {
+			input:    ExportRequest{Currency: "CAD", Filter: ConversionFilter{From: "yesterday"}},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: ExportRequest{Currency: "CAD"},
+			mockRepo: &stubRepository{
+				list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+					return nil, someErr
+				},
+			},
+			wantErr: someErr,
+		},
+		"write error": {
+			input: ExportRequest{Currency: "CAD"},
+			mockRepo: &stubRepository{
+				list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+					return []Transactions{{ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"}}, nil
+				},
+			},
+			write: func(row ExportRow) error {
+				return someErr
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockGw := &stubProvider{
+				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+					return &gateway.CurrencyExchangeRate{ExchangeRate: "1.35"}, nil
+				},
+			}
+
+			write := tc.write
+			if write == nil {
+				write = func(row ExportRow) error {
+					t.Fatal("unexpected write")
+					return nil
+				}
+			}
+
+			svc := NewService(tc.mockRepo, mockGw, nil, RateSelection{})
+			gotErr := svc.Export(context.Background(), tc.input, write)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestExportRow_Record(t *testing.T) {
+	rate := money.MustParseRate("1.35")
+	converted := money.MustParse("13.50", "CAD")
+
+	row := ExportRow{
+		ID:                  "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:         "food, drinks",
+		TransactionDate:     time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		OriginalAmount:      money.MustParse("10", money.USD),
+		ExchangeRate:        &rate,
+		RateDate:            "2023-06-30",
+		ConvertedAmount:     &converted,
+		CountryCurrencyDesc: "Canada-Dollar",
+		RateProvider:        "treasury",
+	}
+
+	assert.Equal(t, []string{
+		"b62a64c9-0008-4148-99f6-9c8086a1dd42", "food, drinks", "2023-09-21T00:00:00Z", "10.00",
+		"1.35", "2023-06-30", "13.50", "Canada-Dollar", "treasury", "",
+	}, row.Record())
+	assert.Len(t, ExportHeader, len(row.Record()))
+}
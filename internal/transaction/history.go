@@ -0,0 +1,115 @@
+package transaction
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+// Kinds of change recorded in the transaction history.
+const (
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// UpdateRequest represents a correction of a transaction. Only the fields set are changed.
+// Actor identifies who makes the change and is recorded in the history. Version, when not zero,
+// is the version the transaction must still be at for the change to be made.
+type UpdateRequest struct {
+	ID              string       `json:"-"`
+	Actor           string       `json:"-"`
+	Version         int          `json:"-"`
+	Description     *string      `json:"description"`
+	TransactionDate *time.Time   `json:"transaction_date"`
+	Amount          *money.Money `json:"amount"`
+}
+
+// DeleteRequest represents a request to void a transaction.
+// Actor and Version are as in UpdateRequest.
+type DeleteRequest struct {
+	ID      string
+	Actor   string
+	Version int
+}
+
+// Revision represents a prior version of a transaction along with the change that replaced it.
+type Revision struct {
+	Description     string      `json:"description"`
+	TransactionDate time.Time   `json:"transaction_date"`
+	Amount          money.Money `json:"amount"`
+	Change          string      `json:"change"`
+	ChangedBy       string      `json:"changed_by"`
+	ChangedAt       time.Time   `json:"changed_at"`
+}
+
+// HistoryResponse represents the prior versions of a transaction, oldest first.
+type HistoryResponse struct {
+	ID   string     `json:"id"`
+	Data []Revision `json:"data"`
+}
+
+// validate checks if the update request data is valid.
+func (r *UpdateRequest) validate() error {
+	if isValidUUID(r.ID) {
+		return errors.New("invalid UUID")
+	}
+
+	if isEmpty(r.Actor) {
+		return errors.New("actor is required")
+	}
+
+	if r.Description == nil && r.TransactionDate == nil && r.Amount == nil {
+		return errors.New("at least one of description, transaction_date or amount is required")
+	}
+
+	if r.Description != nil {
+		if err := validateDescription(*r.Description); err != nil {
+			return err
+		}
+	}
+
+	if r.TransactionDate != nil {
+		if err := validateTransactionDate(*r.TransactionDate); err != nil {
+			return err
+		}
+	}
+
+	if r.Amount != nil {
+		if err := validateAmount(*r.Amount); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply returns the transaction with the changes of the request.
+func (r *UpdateRequest) apply(txn Transactions) Transactions {
+	if r.Description != nil {
+		txn.Description = *r.Description
+	}
+
+	if r.TransactionDate != nil {
+		txn.TransactionDate = r.TransactionDate.UTC()
+	}
+
+	if r.Amount != nil {
+		txn.Amount = money.New(r.Amount.MinorUnits(), money.USD)
+	}
+
+	return txn
+}
+
+// validate checks if the delete request data is valid.
+func (r *DeleteRequest) validate() error {
+	if isValidUUID(r.ID) {
+		return errors.New("invalid UUID")
+	}
+
+	if isEmpty(r.Actor) {
+		return errors.New("actor is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestTransaction_UpdateRequest_Validate_Error(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	description := "food"
+	longDescription := "this is a description longer than fifty characters!!"
+	var zeroDate time.Time
+	negativeAmount := money.MustParse("-1", money.USD)
+
+	testCases := map[string]struct {
+		input   *UpdateRequest
+		wantErr string
+	}{
+		"invalid UUID": {
+			input:   &UpdateRequest{ID: "123", Actor: "jane", Description: &description},
+			wantErr: "invalid UUID",
+		},
+		"missing actor": {
+			input:   &UpdateRequest{ID: id, Description: &description},
+			wantErr: "actor is required",
+		},
+		"no field": {
+			input:   &UpdateRequest{ID: id, Actor: "jane"},
+			wantErr: "at least one of",
+		},
+		"description too long": {
+			input:   &UpdateRequest{ID: id, Actor: "jane", Description: &longDescription},
+			wantErr: "description",
+		},
+		"zero date": {
+			input:   &UpdateRequest{ID: id, Actor: "jane", TransactionDate: &zeroDate},
+			wantErr: "transaction date is required",
+		},
+		"negative amount": {
+			input:   &UpdateRequest{ID: id, Actor: "jane", Amount: &negativeAmount},
+			wantErr: "amount",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotErr := tc.input.validate()
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestTransaction_UpdateRequest_Apply(t *testing.T) {
+	txn := Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.20", money.USD),
+	}
+
+	description := "groceries"
+	date := time.Date(2023, time.September, 20, 21, 0, 0, 0, time.FixedZone("BRT", -3*60*60))
+	amount := money.MustParse("25", "")
+
+	testCases := map[string]struct {
+		input *UpdateRequest
+		want  Transactions
+	}{
+		"description only": {
+			input: &UpdateRequest{Description: &description},
+			want: Transactions{
+				ID:              txn.ID,
+				Description:     description,
+				TransactionDate: txn.TransactionDate,
+				Amount:          txn.Amount,
+			},
+		},
+		"all fields": {
+			input: &UpdateRequest{Description: &description, TransactionDate: &date, Amount: &amount},
+			want: Transactions{
+				ID:              txn.ID,
+				Description:     description,
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Amount:          money.MustParse("25", money.USD),
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got := tc.input.apply(txn)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTransaction_DeleteRequest_Validate_Error(t *testing.T) {
+	testCases := map[string]struct {
+		input   *DeleteRequest
+		wantErr string
+	}{
+		"invalid UUID": {
+			input:   &DeleteRequest{ID: "123", Actor: "jane"},
+			wantErr: "invalid UUID",
+		},
+		"missing actor": {
+			input:   &DeleteRequest{ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+			wantErr: "actor is required",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			gotErr := tc.input.validate()
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package transaction
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+// MaxImportRows is the largest number of rows imported in a single request.
+const MaxImportRows = 100000
+
+// Formats of an import file.
+const (
+	ImportCSV   = "csv"
+	ImportJSONL = "jsonl"
+)
+
+// maxImportLineSize is the largest line of a JSONL import file.
+const maxImportLineSize = 64 * 1024
+
+// importColumns are the columns required in the header of a CSV import file.
+var importColumns = []string{"description", "transaction_date", "amount"}
+
+// ImportRequest represents a file of transactions to import, in the ImportCSV or ImportJSONL format.
+// A CSV file starts with a header naming the description, transaction_date and amount columns, in any order;
+// a JSONL file has a RecordRequest object on each line.
+type ImportRequest struct {
+	Format string
+	Body   io.Reader
+}
+
+// ImportResponse represents the outcome of every row of an import file.
+type ImportResponse struct {
+	Accepted int         `json:"accepted"`
+	Rejected int         `json:"rejected"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// ImportRow represents the ID of the transaction created for a row, or the reason the row was rejected.
+// Line is the line of the file the row starts at.
+type ImportRow struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// importRecord represents a row read from an import file, or the reason it could not be read.
+type importRecord struct {
+	line  int
+	input RecordRequest
+	err   error
+}
+
+// readImport reads the rows of an import file in the given format.
+func readImport(format string, body io.Reader) ([]importRecord, error) {
+	var (
+		records []importRecord
+		err     error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case ImportCSV:
+		records, err = readImportCSV(body)
+	case ImportJSONL:
+		records, err = readImportJSONL(body)
+	default:
+		return nil, fmt.Errorf("format must be %s or %s", ImportCSV, ImportJSONL)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("file has no rows")
+	}
+
+	return records, nil
+}
+
+// readImportCSV reads the rows of a CSV import file. A row with the wrong number of fields is rejected,
+// while a malformed file fails the whole import.
+func readImportCSV(body io.Reader) ([]importRecord, error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header must have a %s column", name)
+		}
+	}
+
+	var records []importRecord
+	for {
+		fields, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if len(records) == MaxImportRows {
+			return nil, fmt.Errorf("file must not exceed %d rows", MaxImportRows)
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				records = append(records, importRecord{
+					line: parseErr.StartLine,
+					err:  fmt.Errorf("row must have %d fields", len(header)),
+				})
+				continue
+			}
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		line, _ := r.FieldPos(0)
+		input, err := parseImportFields(fields, columns)
+		records = append(records, importRecord{
+			line:  line,
+			input: input,
+			err:   err,
+		})
+	}
+
+	return records, nil
+}
+
+// parseImportFields parses the fields of a CSV row into a record request.
+// Empty fields are left unset, to be reported as required by the validation.
+func parseImportFields(fields []string, columns map[string]int) (RecordRequest, error) {
+	input := RecordRequest{
+		Description: fields[columns["description"]],
+	}
+
+	if date := strings.TrimSpace(fields[columns["transaction_date"]]); date != "" {
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			if t, err = time.Parse(dateFormat, date); err != nil {
+				return RecordRequest{}, errors.New("transaction_date must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+			}
+		}
+		input.TransactionDate = t
+	}
+
+	if amount := strings.TrimSpace(fields[columns["amount"]]); amount != "" {
+		m, err := money.Parse(amount, "")
+		if err != nil {
+			return RecordRequest{}, err
+		}
+		input.Amount = m
+	}
+
+	return input, nil
+}
+
+// readImportJSONL reads the rows of a JSONL import file, skipping blank lines.
+func readImportJSONL(body io.Reader) ([]importRecord, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLineSize)
+
+	var (
+		records []importRecord
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		if len(records) == MaxImportRows {
+			return nil, fmt.Errorf("file must not exceed %d rows", MaxImportRows)
+		}
+
+		rec := importRecord{line: line}
+		if err := json.Unmarshal(text, &rec.input); err != nil {
+			if errors.Is(err, money.ErrInvalidAmount) || errors.Is(err, money.ErrPrecision) || errors.Is(err, money.ErrOverflow) {
+				rec.err = err
+			} else {
+				rec.err = errors.New("invalid JSON object")
+			}
+		}
+
+		records = append(records, rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid JSONL: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,123 @@
+package transaction
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestReadImport(t *testing.T) {
+	date := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		format string
+		body   string
+		want   []importRecord
+	}{
+		"csv": {
+			format: "CSV",
+			body: "\ufeffamount,Description,transaction_date\n" +
+				"20.20,food,2023-09-21T00:00:00Z\n" +
+				"\"1,000.00\",rent,2023-09-21\n" +
+				"5,\"multi\nline\",2023-09-21\n" +
+				"1.001,coffee,2023-09-21\n" +
+				"7,book\n" +
+				"3,lunch,yesterday\n" +
+				",,\n",
+			want: []importRecord{
+				{line: 2, input: RecordRequest{Description: "food", TransactionDate: date, Amount: money.MustParse("20.20", "")}},
+				{line: 3, err: fmt.Errorf("%w: %q", money.ErrInvalidAmount, "1,000.00")},
+				{line: 4, input: RecordRequest{Description: "multi\nline", TransactionDate: date, Amount: money.MustParse("5", "")}},
+				{line: 6, err: money.ErrPrecision},
+				{line: 7, err: fmt.Errorf("row must have 3 fields")},
+				{line: 8, err: fmt.Errorf("transaction_date must be an RFC 3339 timestamp or a YYYY-MM-DD date")},
+				{line: 9},
+			},
+		},
+		"jsonl": {
+			format: ImportJSONL,
+			body: `{"description":"food","transaction_date":"2023-09-21T00:00:00Z","amount":20.2}` + "\n" +
+				"\n" +
+				`{"description":"coffee","amount":1.001}` + "\n" +
+				`{"description":` + "\n",
+			want: []importRecord{
+				{line: 1, input: RecordRequest{Description: "food", TransactionDate: date, Amount: money.MustParse("20.20", "")}},
+				{line: 3, input: RecordRequest{Description: "coffee"}, err: money.ErrPrecision},
+				{line: 4, err: fmt.Errorf("invalid JSON object")},
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := readImport(tc.format, strings.NewReader(tc.body))
+			assert.NoError(t, gotErr)
+			assert.Equal(t, len(tc.want), len(got))
+
+			for i := range tc.want {
+				assert.Equal(t, tc.want[i].line, got[i].line)
+				if tc.want[i].err != nil {
+					assert.EqualError(t, got[i].err, tc.want[i].err.Error())
+					continue
+				}
+				assert.NoError(t, got[i].err)
+				assert.Equal(t, tc.want[i].input, got[i].input)
+			}
+		})
+	}
+}
+
+func TestReadImport_Error(t *testing.T) {
+	testCases := map[string]struct {
+		format  string
+		body    string
+		wantErr string
+	}{
+		"unknown format": {
+			format:  "xml",
+			body:    "<transactions/>",
+			wantErr: "format must be csv or jsonl",
+		},
+		"empty csv": {
+			format:  ImportCSV,
+			wantErr: "file has no rows",
+		},
+		"csv header only": {
+			format:  ImportCSV,
+			body:    "description,transaction_date,amount\n",
+			wantErr: "file has no rows",
+		},
+		"csv missing column": {
+			format:  ImportCSV,
+			body:    "description,amount\nfood,1\n",
+			wantErr: "CSV header must have a transaction_date column",
+		},
+		"malformed csv": {
+			format:  ImportCSV,
+			body:    "description,transaction_date,amount\n\"food,2023-09-21,1\n",
+			wantErr: "invalid CSV",
+		},
+		"blank jsonl": {
+			format:  ImportJSONL,
+			body:    "\n\n",
+			wantErr: "file has no rows",
+		},
+		"jsonl line too long": {
+			format:  ImportJSONL,
+			body:    `{"description":"` + strings.Repeat("a", maxImportLineSize) + `"}`,
+			wantErr: "invalid JSONL",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := readImport(tc.format, strings.NewReader(tc.body))
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+const (
+	dateFormat = "2006-01-02"
+
+	// DefaultListLimit is the number of transactions returned per page when no limit is requested.
+	DefaultListLimit = 50
+
+	// MaxListLimit is the largest number of transactions returned per page.
+	MaxListLimit = 200
+)
+
+// SortField identifies the column transactions are sorted by.
+type SortField string
+
+const (
+	// SortByDate sorts transactions by transaction date.
+	SortByDate SortField = "transaction_date"
+
+	// SortByAmount sorts transactions by amount.
+	SortByAmount SortField = "amount"
+)
+
+// Sort represents the order of a transaction list. Ties are broken by transaction ID.
+type Sort struct {
+	Field      SortField
+	Descending bool
+}
+
+// DefaultSort lists the most recent transactions first.
+var DefaultSort = Sort{Field: SortByDate, Descending: true}
+
+// String returns the sort in its query parameter form, e.g. "-transaction_date".
+func (s Sort) String() string {
+	if s.Descending {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// parseSort parses a sort query parameter such as "amount" or "-transaction_date".
+func parseSort(s string) (Sort, error) {
+	if isEmpty(s) {
+		return DefaultSort, nil
+	}
+
+	sort := Sort{Field: SortField(s)}
+	if s[0] == '-' {
+		sort = Sort{Field: SortField(s[1:]), Descending: true}
+	}
+
+	switch sort.Field {
+	case SortByDate, SortByAmount:
+		return sort, nil
+	default:
+		return Sort{}, fmt.Errorf("invalid sort %q", s)
+	}
+}
+
+// Cursor represents the position of the last transaction of a page, used to fetch the next page.
+type Cursor struct {
+	Sort            string    `json:"s"`
+	TransactionDate time.Time `json:"d"`
+	Amount          int64     `json:"a"`
+	ID              string    `json:"i"`
+}
+
+// newCursor creates the cursor pointing after the given transaction.
+func newCursor(sort Sort, txn Transactions) Cursor {
+	return Cursor{
+		Sort:            sort.String(),
+		TransactionDate: txn.TransactionDate,
+		Amount:          txn.Amount.MinorUnits(),
+		ID:              txn.ID,
+	}
+}
+
+// encode returns the cursor as an opaque URL-safe token.
+func (c Cursor) encode() string {
+	body, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+// decodeCursor decodes a token created by Cursor.encode.
+func decodeCursor(token string) (*Cursor, error) {
+	body, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(body, &c); err != nil || isEmpty(c.ID) {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// ListFilter represents the criteria used by the repository to list transactions.
+type ListFilter struct {
+	DateFrom    time.Time // inclusive, zero for no lower bound
+	DateTo      time.Time // exclusive, zero for no upper bound
+	MinAmount   *money.Money
+	MaxAmount   *money.Money
+	Description string
+	Sort        Sort
+	Limit       int
+	After       *Cursor
+}
+
+// ListRequest represents a request to list transactions provided by the user.
+type ListRequest struct {
+	From        string
+	To          string
+	MinAmount   string
+	MaxAmount   string
+	Description string
+	Sort        string
+	Limit       string
+	Cursor      string
+}
+
+// ListResponse represents a page of transactions.
+type ListResponse struct {
+	Data       []TransactionResponse `json:"data"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// TransactionResponse represents a stored transaction in its original currency.
+type TransactionResponse struct {
+	ID              string      `json:"id"`
+	Description     string      `json:"description"`
+	TransactionDate time.Time   `json:"transaction_date"`
+	Amount          money.Money `json:"amount"`
+
+	// Version is the version of the transaction, sent as its ETag when a single transaction is returned.
+	Version int `json:"-"`
+}
+
+// filter validates the list request and converts it into the repository filter.
+func (r *ListRequest) filter() (ListFilter, error) {
+	var (
+		f   ListFilter
+		err error
+	)
+
+	if f.DateFrom, err = parseDate(r.From, "from"); err != nil {
+		return ListFilter{}, err
+	}
+
+	if f.DateTo, err = parseDate(r.To, "to"); err != nil {
+		return ListFilter{}, err
+	}
+	if !f.DateTo.IsZero() {
+		f.DateTo = f.DateTo.AddDate(0, 0, 1)
+	}
+
+	if !f.DateFrom.IsZero() && !f.DateTo.IsZero() && !f.DateFrom.Before(f.DateTo) {
+		return ListFilter{}, errors.New("from must not be after to")
+	}
+
+	if f.MinAmount, err = parseAmount(r.MinAmount, "min_amount"); err != nil {
+		return ListFilter{}, err
+	}
+
+	if f.MaxAmount, err = parseAmount(r.MaxAmount, "max_amount"); err != nil {
+		return ListFilter{}, err
+	}
+
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.MinorUnits() > f.MaxAmount.MinorUnits() {
+		return ListFilter{}, errors.New("min_amount must not be greater than max_amount")
+	}
+
+	if len(r.Description) > 50 {
+		return ListFilter{}, errors.New("description must not exceed 50 characters")
+	}
+	f.Description = r.Description
+
+	if f.Sort, err = parseSort(r.Sort); err != nil {
+		return ListFilter{}, err
+	}
+
+	if f.Limit, err = parseLimit(r.Limit); err != nil {
+		return ListFilter{}, err
+	}
+
+	if !isEmpty(r.Cursor) {
+		if f.After, err = decodeCursor(r.Cursor); err != nil {
+			return ListFilter{}, err
+		}
+
+		if f.After.Sort != f.Sort.String() {
+			return ListFilter{}, errors.New("cursor does not match the requested sort")
+		}
+	}
+
+	return f, nil
+}
+
+// parseDate parses an optional date in the YYYY-MM-DD format.
+func parseDate(s, name string) (time.Time, error) {
+	if isEmpty(s) {
+		return time.Time{}, nil
+	}
+
+	date, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date in the YYYY-MM-DD format", name)
+	}
+
+	return date, nil
+}
+
+// parseAmount parses an optional amount in USD.
+func parseAmount(s, name string) (*money.Money, error) {
+	if isEmpty(s) {
+		return nil, nil
+	}
+
+	amount, err := money.Parse(s, money.USD)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &amount, nil
+}
+
+// parseLimit parses the optional page size.
+func parseLimit(s string) (int, error) {
+	if isEmpty(s) {
+		return DefaultListLimit, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > MaxListLimit {
+		return 0, fmt.Errorf("limit must be a number between 1 and %d", MaxListLimit)
+	}
+
+	return limit, nil
+}
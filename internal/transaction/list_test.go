@@ -0,0 +1,131 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestTransaction_ListRequest_Filter(t *testing.T) {
+	cursor := Cursor{
+		Sort:            "amount",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          2312,
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+	}
+	minAmount := money.MustParse("10", money.USD)
+	maxAmount := money.MustParse("99.99", money.USD)
+
+	testCases := map[string]struct {
+		input *ListRequest
+		want  ListFilter
+	}{
+		"defaults": {
+			input: &ListRequest{},
+			want: ListFilter{
+				Sort:  DefaultSort,
+				Limit: DefaultListLimit,
+			},
+		},
+		"all filters": {
+			input: &ListRequest{
+				From:        "2023-09-01",
+				To:          "2023-09-30",
+				MinAmount:   "10",
+				MaxAmount:   "99.99",
+				Description: "food",
+				Sort:        "amount",
+				Limit:       "10",
+				Cursor:      cursor.encode(),
+			},
+			want: ListFilter{
+				DateFrom:    time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+				DateTo:      time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC),
+				MinAmount:   &minAmount,
+				MaxAmount:   &maxAmount,
+				Description: "food",
+				Sort:        Sort{Field: SortByAmount},
+				Limit:       10,
+				After:       &cursor,
+			},
+		},
+		"descending sort": {
+			input: &ListRequest{Sort: "-amount"},
+			want: ListFilter{
+				Sort:  Sort{Field: SortByAmount, Descending: true},
+				Limit: DefaultListLimit,
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			got, gotErr := tc.input.filter()
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTransaction_ListRequest_Filter_Error(t *testing.T) {
+	descCursor := Cursor{Sort: "-transaction_date", ID: "b62a64c9-0008-4148-99f6-9c8086a1dd42"}
+
+	testCases := map[string]struct {
+		input   *ListRequest
+		wantErr string
+	}{
+		"invalid from": {
+			input:   &ListRequest{From: "01/09/2023"},
+			wantErr: "from must be a date",
+		},
+		"invalid to": {
+			input:   &ListRequest{To: "2023-09-31"},
+			wantErr: "to must be a date",
+		},
+		"from after to": {
+			input:   &ListRequest{From: "2023-10-01", To: "2023-09-30"},
+			wantErr: "from must not be after to",
+		},
+		"invalid min amount": {
+			input:   &ListRequest{MinAmount: "ten"},
+			wantErr: "min_amount",
+		},
+		"max amount with more than two decimals": {
+			input:   &ListRequest{MaxAmount: "1.001"},
+			wantErr: "two decimal places",
+		},
+		"min amount greater than max amount": {
+			input:   &ListRequest{MinAmount: "10", MaxAmount: "5"},
+			wantErr: "must not be greater than max_amount",
+		},
+		"description more than 50 characters": {
+			input:   &ListRequest{Description: "more than 50 characters, more than 50 characters!!!"},
+			wantErr: "not exceed 50 characters",
+		},
+		"invalid sort": {
+			input:   &ListRequest{Sort: "description"},
+			wantErr: "invalid sort",
+		},
+		"limit too large": {
+			input:   &ListRequest{Limit: "1000"},
+			wantErr: "limit must be a number",
+		},
+		"invalid cursor": {
+			input:   &ListRequest{Cursor: "not-a-cursor"},
+			wantErr: "invalid cursor",
+		},
+		"cursor from another sort": {
+			input:   &ListRequest{Sort: "amount", Cursor: descCursor.encode()},
+			wantErr: "cursor does not match",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			_, gotErr := tc.input.filter()
+			assert.ErrorContains(t, gotErr, tc.wantErr)
+		})
+	}
+}
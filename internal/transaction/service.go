@@ -2,89 +2,552 @@ package transaction
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
+	"time"
 
+	"github.com/vickiliou/challenge-wex/internal/currency"
 	"github.com/vickiliou/challenge-wex/internal/gateway"
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"github.com/vickiliou/challenge-wex/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type repository interface {
 	Create(ctx context.Context, txn Transactions) (string, error)
+	CreateIdempotent(ctx context.Context, txn Transactions, res idempotency.Response) (string, error)
+	CreateMany(ctx context.Context, txns []Transactions) error
 	FindByID(ctx context.Context, id string) (*Transactions, error)
+	FindByIDs(ctx context.Context, ids []string) ([]Transactions, error)
+	List(ctx context.Context, filter ListFilter) ([]Transactions, error)
+	Update(ctx context.Context, txn Transactions, actor string) error
+	Delete(ctx context.Context, id, actor string, version int) error
+	History(ctx context.Context, id string) ([]Revision, error)
+	AmountsByDay(ctx context.Context, filter ListFilter) ([]DayAmount, error)
 }
 
-type gatewayExchangeRate interface {
-	GetExchangeRate(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
+type exchangeRateProvider interface {
+	GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
 }
 
+// tracerName is the name of the tracer of the service methods.
+const tracerName = "github.com/vickiliou/challenge-wex/internal/transaction"
+
 type uuidGenerator func() string
 
 // Service represents the transaction service that encapsulates the business logic related to transactions.
 type Service struct {
-	repo        repository
-	gw          gatewayExchangeRate
-	idGenerator uuidGenerator
+	repo          repository
+	rates         exchangeRateProvider
+	idGenerator   uuidGenerator
+	rateSelection RateSelection
 }
 
 // NewService creates a new instance of the transaction service.
-func NewService(repo repository, gw gatewayExchangeRate, idGenerator uuidGenerator) *Service {
+// rateSelection is how exchange rates are selected unless a request chooses otherwise;
+// its zero fields default to gateway.DefaultPolicy and gateway.DefaultLookbackMonths.
+func NewService(repo repository, rates exchangeRateProvider, idGenerator uuidGenerator, rateSelection RateSelection) *Service {
+	if rateSelection.Policy == "" {
+		rateSelection.Policy = gateway.DefaultPolicy
+	}
+
+	if rateSelection.LookbackMonths == 0 {
+		rateSelection.LookbackMonths = gateway.DefaultLookbackMonths
+	}
+
 	return &Service{
-		repo:        repo,
-		gw:          gw,
-		idGenerator: idGenerator,
+		repo:          repo,
+		rates:         rates,
+		idGenerator:   idGenerator,
+		rateSelection: rateSelection,
 	}
 }
 
+// startSpan starts the span of a method of the service.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "Service."+method)
+}
+
+// endSpan ends the span of a method of the service, recording err unless it is caused by the request,
+// e.g. a validation error or a transaction that does not exist.
+func endSpan(span trace.Span, err error) {
+	tracing.EndSpan(span, err,
+		httpresponse.ErrValidation,
+		httpresponse.ErrNotFound,
+		httpresponse.ErrPreconditionFailed,
+		httpresponse.ErrNoCurrencyConversion,
+	)
+}
+
 // Create creates a new transaction based on user input.
-func (s *Service) Create(ctx context.Context, input RecordRequest) (string, error) {
+// With an idempotency key, the response replayed for the key is stored in the same database transaction.
+func (s *Service) Create(ctx context.Context, input RecordRequest) (_ string, err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { endSpan(span, err) }()
+
 	if err := input.validate(); err != nil {
 		return "", fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
 	}
 
-	txn := Transactions{
+	txn := s.newTransaction(input)
+	if input.Idempotency == nil {
+		return s.repo.Create(ctx, txn)
+	}
+
+	body, err := json.Marshal(RecordResponse{ID: txn.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	return s.repo.CreateIdempotent(ctx, txn, idempotency.Response{
+		Reservation: *input.Idempotency,
+		StatusCode:  http.StatusCreated,
+		Body:        body,
+	})
+}
+
+// Import creates the transactions of a CSV or JSONL file. Each row is validated as in Create, and the valid rows
+// are all stored in a single database transaction, or none at all if storing them fails. Invalid rows are
+// reported with the reason they were rejected, without failing the other rows. A file larger than the limit of
+// an http.MaxBytesReader body fails with its *http.MaxBytesError.
+func (s *Service) Import(ctx context.Context, input ImportRequest) (_ *ImportResponse, err error) {
+	ctx, span := startSpan(ctx, "Import")
+	defer func() { endSpan(span, err) }()
+
+	records, err := readImport(input.Format, input.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("error reading import file: %w", err)
+		}
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	res := &ImportResponse{
+		Rows: make([]ImportRow, 0, len(records)),
+	}
+	txns := make([]Transactions, 0, len(records))
+
+	for _, rec := range records {
+		row := ImportRow{Line: rec.line}
+
+		err := rec.err
+		if err == nil {
+			err = rec.input.validate()
+		}
+
+		if err != nil {
+			row.Error = err.Error()
+			res.Rejected++
+		} else {
+			txn := s.newTransaction(rec.input)
+			txns = append(txns, txn)
+			row.ID = txn.ID
+			res.Accepted++
+		}
+
+		res.Rows = append(res.Rows, row)
+	}
+
+	if len(txns) > 0 {
+		if err := s.repo.CreateMany(ctx, txns); err != nil {
+			return nil, fmt.Errorf("error calling database: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// newTransaction returns a new transaction in USD with the fields of a valid record request.
+func (s *Service) newTransaction(input RecordRequest) Transactions {
+	return Transactions{
 		ID:              s.idGenerator(),
 		Description:     input.Description,
-		TransactionDate: input.TransactionDate,
-		Amount:          input.Amount,
+		TransactionDate: input.TransactionDate.UTC(),
+		Amount:          money.New(input.Amount.MinorUnits(), money.USD),
 	}
-
-	return s.repo.Create(ctx, txn)
 }
 
 // Get retrieves a transaction by its ID.
-func (s *Service) Get(ctx context.Context, input RetrieveRequest) (*RetrieveResponse, error) {
+func (s *Service) Get(ctx context.Context, input RetrieveRequest) (_ *RetrieveResponse, err error) {
+	ctx, span := startSpan(ctx, "Get")
+	defer func() { endSpan(span, err) }()
+
 	if err := input.validate(); err != nil {
 		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
 	}
 
+	target, err := currency.Resolve(input.Country, input.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	selection, err := input.rateSelection(s.rateSelection)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
 	txn, err := s.repo.FindByID(ctx, input.ID)
 	if err != nil {
 		return nil, fmt.Errorf("error calling database: %w", err)
 	}
 
-	inputGw := gateway.CurrencyExchangeRateRequest{
-		TransactionDate: txn.TransactionDate,
-		Country:         input.Country,
-		Currency:        input.Currency,
+	inputGw := exchangeRateRequest(txn.TransactionDate, target, selection)
+
+	exchangeRate, err := s.getExchangeRate(ctx, inputGw)
+	if err != nil {
+		return nil, err
+	}
+
+	etag := retrieveETag(*txn, exchangeRate)
+	if httpresponse.IfNoneMatch(input.IfNoneMatch, etag) {
+		return &RetrieveResponse{ID: txn.ID, Version: txn.Version, ETag: etag, NotModified: true}, nil
+	}
+
+	res, err := convert(*txn, exchangeRate, currencyCode(target))
+	if err != nil {
+		return nil, err
+	}
+	res.ETag = etag
+
+	return res, nil
+}
+
+// retrieveETag returns the entity tag of a converted transaction. The exchange rate can change without the
+// transaction changing, e.g. when rates are synced or overridden, so the tag covers both.
+func retrieveETag(txn Transactions, exchangeRate *gateway.CurrencyExchangeRate) string {
+	return httpresponse.FormatETag(txn.Version,
+		exchangeRate.RecordDate,
+		exchangeRate.ExchangeRate,
+		exchangeRate.CountryCurrencyDesc,
+		exchangeRate.Provider,
+	)
+}
+
+// List retrieves a page of transactions matching the filters of the request.
+func (s *Service) List(ctx context.Context, input ListRequest) (_ *ListResponse, err error) {
+	ctx, span := startSpan(ctx, "List")
+	defer func() { endSpan(span, err) }()
+
+	filter, err := input.filter()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
 	}
 
-	exchangeRate, err := s.gw.GetExchangeRate(inputGw)
+	limit := filter.Limit
+	filter.Limit++
+
+	txns, err := s.repo.List(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("error calling gateway: %w", err)
+		return nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	res := &ListResponse{
+		Data: make([]TransactionResponse, 0, len(txns)),
+	}
+
+	if len(txns) > limit {
+		txns = txns[:limit]
+		res.NextCursor = newCursor(filter.Sort, txns[limit-1]).encode()
+	}
+
+	for _, txn := range txns {
+		res.Data = append(res.Data, TransactionResponse{
+			ID:              txn.ID,
+			Description:     txn.Description,
+			TransactionDate: txn.TransactionDate,
+			Amount:          txn.Amount,
+		})
 	}
 
-	exchangeRateFloat, err := strconv.ParseFloat(exchangeRate.ExchangeRate, 64)
+	return res, nil
+}
+
+// Update corrects a transaction with the fields of the request, recording its prior version in the history.
+// The change is only made if the transaction is still at the version it was read at, and at the version
+// of the request if any, so that concurrent changes are never silently overwritten.
+func (s *Service) Update(ctx context.Context, input UpdateRequest) (_ *TransactionResponse, err error) {
+	ctx, span := startSpan(ctx, "Update")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	txn, err := s.repo.FindByID(ctx, input.ID)
 	if err != nil {
-		return nil, fmt.Errorf("error converting exchange rate: %w", err)
+		return nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	if input.Version != 0 && input.Version != txn.Version {
+		return nil, fmt.Errorf("%w: current version is %d", httpresponse.ErrPreconditionFailed, txn.Version)
+	}
+
+	updated := input.apply(*txn)
+	if err := s.repo.Update(ctx, updated, input.Actor); err != nil {
+		return nil, fmt.Errorf("error calling database: %w", err)
 	}
 
-	return &RetrieveResponse{
-		ID:              txn.ID,
-		Description:     txn.Description,
-		TransactionDate: txn.TransactionDate,
-		OriginalAmount:  txn.Amount,
-		ExchangeRate:    exchangeRateFloat,
-		ConvertedAmount: roundTwoDecimal(exchangeRateFloat * txn.Amount),
+	return &TransactionResponse{
+		ID:              updated.ID,
+		Description:     updated.Description,
+		TransactionDate: updated.TransactionDate,
+		Amount:          updated.Amount,
+		Version:         updated.Version + 1,
 	}, nil
 }
+
+// Delete voids a transaction, recording its last version in the history. A deleted transaction is no longer
+// retrieved, listed or converted, but its history is kept. With a version in the request, the transaction
+// is only deleted if it is still at that version.
+func (s *Service) Delete(ctx context.Context, input DeleteRequest) (err error) {
+	ctx, span := startSpan(ctx, "Delete")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.validate(); err != nil {
+		return fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	if err := s.repo.Delete(ctx, input.ID, input.Actor, input.Version); err != nil {
+		return fmt.Errorf("error calling database: %w", err)
+	}
+
+	return nil
+}
+
+// History retrieves the prior versions of a transaction, including a deleted one.
+func (s *Service) History(ctx context.Context, id string) (_ *HistoryResponse, err error) {
+	ctx, span := startSpan(ctx, "History")
+	defer func() { endSpan(span, err) }()
+
+	if isValidUUID(id) {
+		return nil, fmt.Errorf("%w: invalid UUID", httpresponse.ErrValidation)
+	}
+
+	revisions, err := s.repo.History(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	res := &HistoryResponse{
+		ID:   id,
+		Data: make([]Revision, 0, len(revisions)),
+	}
+	res.Data = append(res.Data, revisions...)
+
+	return res, nil
+}
+
+// ConvertBatch converts many transactions to the target currency. Transactions are grouped by the
+// exchange rate period that applies to them so that each distinct rate is fetched only once.
+// Transactions that cannot be converted are reported with an error instead of failing the whole request.
+func (s *Service) ConvertBatch(ctx context.Context, input ConversionRequest) (_ *ConversionResponse, err error) {
+	ctx, span := startSpan(ctx, "ConvertBatch")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	target, err := currency.Resolve(input.Country, input.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	selection, err := s.rateSelection.override(input.RatePolicy, input.LookbackMonths)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	ids, txns, err := s.findForConversion(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Transactions, len(txns))
+	for _, txn := range txns {
+		byID[txn.ID] = txn
+	}
+
+	rates := s.exchangeRatesByDay(ctx, distinctDays(txns), target, selection)
+
+	res := &ConversionResponse{
+		Data: make([]ConversionResult, 0, len(ids)),
+	}
+
+	for _, id := range ids {
+		result := ConversionResult{ID: id}
+
+		txn, ok := byID[id]
+		switch {
+		case isValidUUID(id):
+			result.Error = "invalid UUID"
+		case !ok:
+			result.Error = fmt.Errorf("%w transaction ID %s", httpresponse.ErrNotFound, id).Error()
+		case rates[transactionDay(txn)].err != nil:
+			result.Error = rates[transactionDay(txn)].err.Error()
+		default:
+			converted, err := convert(txn, rates[transactionDay(txn)].rate, currencyCode(target))
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Result = converted
+			}
+		}
+
+		res.Data = append(res.Data, result)
+	}
+
+	return res, nil
+}
+
+// findForConversion retrieves the transactions of a conversion request.
+// It returns the IDs in the order results are reported along with the transactions found.
+func (s *Service) findForConversion(ctx context.Context, input ConversionRequest) ([]string, []Transactions, error) {
+	if input.Filter != nil {
+		filter, err := input.Filter.listFilter()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+		}
+
+		txns, err := s.repo.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error calling database: %w", err)
+		}
+
+		if len(txns) > MaxConversionItems {
+			return nil, nil, fmt.Errorf("%w: filter must not match more than %d transactions", httpresponse.ErrValidation, MaxConversionItems)
+		}
+
+		ids := make([]string, 0, len(txns))
+		for _, txn := range txns {
+			ids = append(ids, txn.ID)
+		}
+
+		return ids, txns, nil
+	}
+
+	validIDs := make([]string, 0, len(input.IDs))
+	for _, id := range input.IDs {
+		if !isValidUUID(id) {
+			validIDs = append(validIDs, id)
+		}
+	}
+
+	txns, err := s.repo.FindByIDs(ctx, validIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	return input.IDs, txns, nil
+}
+
+// dayExchangeRate represents the exchange rate applicable to a transaction day, or why there is none.
+type dayExchangeRate struct {
+	rate *gateway.CurrencyExchangeRate
+	err  error
+}
+
+// exchangeRatesByDay resolves the exchange rate of every distinct transaction day.
+// Days are visited from the most recent. With the on or before policy, the rate found for a day is the most recent
+// one on or before it, so it is also the most recent one for every earlier day down to its record date, and still
+// within their window. Other policies resolve every day on its own.
+func (s *Service) exchangeRatesByDay(ctx context.Context, days []time.Time, target currency.Currency, selection RateSelection) map[time.Time]dayExchangeRate {
+	rates := make(map[time.Time]dayExchangeRate, len(days))
+
+	for i := 0; i < len(days); {
+		day := days[i]
+		i++
+
+		rate, err := s.getExchangeRate(ctx, exchangeRateRequest(day, target, selection))
+		rates[day] = dayExchangeRate{rate: rate, err: err}
+		if err != nil || selection.Policy != gateway.PolicyOnOrBefore {
+			continue
+		}
+
+		recordDate, err := time.Parse(dateFormat, rate.RecordDate)
+		if err != nil {
+			continue
+		}
+
+		for ; i < len(days) && !days[i].Before(recordDate); i++ {
+			rates[days[i]] = dayExchangeRate{rate: rate}
+		}
+	}
+
+	return rates
+}
+
+// exchangeRateRequest creates the request of the exchange rate of the target currency applying to the transaction date.
+func exchangeRateRequest(transactionDate time.Time, target currency.Currency, selection RateSelection) gateway.CurrencyExchangeRateRequest {
+	return gateway.CurrencyExchangeRateRequest{
+		TransactionDate: transactionDate,
+		Country:         target.TreasuryCountry,
+		Currency:        target.TreasuryCurrency,
+		LookbackMonths:  selection.LookbackMonths,
+		Policy:          selection.Policy,
+	}
+}
+
+// getExchangeRate resolves the exchange rate from the configured providers.
+func (s *Service) getExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	exchangeRate, err := s.rates.GetExchangeRate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error calling exchange rate provider: %w", err)
+	}
+
+	return exchangeRate, nil
+}
+
+// currencyCode returns the ISO code of the target currency, or its Treasury name if it is not mapped.
+func currencyCode(target currency.Currency) string {
+	if target.Code != "" {
+		return target.Code
+	}
+	return target.TreasuryCurrency
+}
+
+// convert converts the transaction amount with the exchange rate into the target currency.
+func convert(txn Transactions, exchangeRate *gateway.CurrencyExchangeRate, currency string) (*RetrieveResponse, error) {
+	rate, err := money.ParseRate(exchangeRate.ExchangeRate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing exchange rate: %w", err)
+	}
+
+	convertedAmount, err := txn.Amount.Convert(rate, currency)
+	if err != nil {
+		return nil, fmt.Errorf("error converting amount: %w", err)
+	}
+
+	res := &RetrieveResponse{
+		ID:                  txn.ID,
+		Description:         txn.Description,
+		TransactionDate:     txn.TransactionDate,
+		OriginalAmount:      txn.Amount,
+		ExchangeRate:        rate,
+		ConvertedAmount:     convertedAmount,
+		CountryCurrencyDesc: exchangeRate.CountryCurrencyDesc,
+		RateProvider:        exchangeRate.Provider,
+		Version:             txn.Version,
+	}
+
+	if exchangeRate.RecordDate != "" {
+		recordDate, err := time.Parse(dateFormat, exchangeRate.RecordDate)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing exchange rate date: %w", err)
+		}
+
+		y, m, d := txn.TransactionDate.Date()
+		age := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(recordDate).Hours() / 24)
+
+		res.RateDate = exchangeRate.RecordDate
+		res.RateAgeDays = &age
+	}
+
+	return res, nil
+}
@@ -3,19 +3,48 @@ package transaction
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vickiliou/challenge-wex/internal/currency"
 	"github.com/vickiliou/challenge-wex/internal/gateway"
 	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type stubRepository struct {
 	receivedCreateInput Transactions
 	create              func(ctx context.Context, txn Transactions) (string, error)
+	receivedResponse    idempotency.Response
+	receivedCreateMany  []Transactions
+	createMany          func(ctx context.Context, txns []Transactions) error
 	receivedFindInput   string
 	findByID            func(ctx context.Context, id string) (*Transactions, error)
+	receivedFindIDs     []string
+	findByIDs           func(ctx context.Context, ids []string) ([]Transactions, error)
+	receivedListInput   ListFilter
+	list                func(ctx context.Context, filter ListFilter) ([]Transactions, error)
+	receivedUpdateInput Transactions
+	receivedActor       string
+	update              func(ctx context.Context, txn Transactions, actor string) error
+	receivedDeleteID    string
+	receivedVersion     int
+	delete              func(ctx context.Context, id, actor string, version int) error
+	history             func(ctx context.Context, id string) ([]Revision, error)
+	amountsByDay        func(ctx context.Context, filter ListFilter) ([]DayAmount, error)
 }
 
 func (s *stubRepository) Create(ctx context.Context, txn Transactions) (string, error) {
@@ -23,18 +52,62 @@ func (s *stubRepository) Create(ctx context.Context, txn Transactions) (string,
 	return s.create(ctx, txn)
 }
 
+func (s *stubRepository) CreateIdempotent(ctx context.Context, txn Transactions, res idempotency.Response) (string, error) {
+	s.receivedResponse = res
+	return s.Create(ctx, txn)
+}
+
+func (s *stubRepository) CreateMany(ctx context.Context, txns []Transactions) error {
+	s.receivedCreateMany = txns
+	return s.createMany(ctx, txns)
+}
+
 func (s *stubRepository) FindByID(ctx context.Context, id string) (*Transactions, error) {
 	s.receivedFindInput = id
 	return s.findByID(ctx, id)
 }
 
-type stubGateway struct {
-	receivedGwInput gateway.CurrencyExchangeRateRequest
+func (s *stubRepository) FindByIDs(ctx context.Context, ids []string) ([]Transactions, error) {
+	s.receivedFindIDs = ids
+	return s.findByIDs(ctx, ids)
+}
+
+func (s *stubRepository) List(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+	s.receivedListInput = filter
+	return s.list(ctx, filter)
+}
+
+func (s *stubRepository) Update(ctx context.Context, txn Transactions, actor string) error {
+	s.receivedUpdateInput = txn
+	s.receivedActor = actor
+	return s.update(ctx, txn, actor)
+}
+
+func (s *stubRepository) Delete(ctx context.Context, id, actor string, version int) error {
+	s.receivedDeleteID = id
+	s.receivedActor = actor
+	s.receivedVersion = version
+	return s.delete(ctx, id, actor, version)
+}
+
+func (s *stubRepository) History(ctx context.Context, id string) ([]Revision, error) {
+	return s.history(ctx, id)
+}
+
+func (s *stubRepository) AmountsByDay(ctx context.Context, filter ListFilter) ([]DayAmount, error) {
+	s.receivedListInput = filter
+	return s.amountsByDay(ctx, filter)
+}
+
+type stubProvider struct {
+	receivedInput   gateway.CurrencyExchangeRateRequest
+	receivedInputs  []gateway.CurrencyExchangeRateRequest
 	getExchangeRate func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error)
 }
 
-func (s *stubGateway) GetExchangeRate(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
-	s.receivedGwInput = input
+func (s *stubProvider) GetExchangeRate(ctx context.Context, input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+	s.receivedInput = input
+	s.receivedInputs = append(s.receivedInputs, input)
 	return s.getExchangeRate(input)
 }
 
@@ -54,7 +127,7 @@ func TestService_Create(t *testing.T) {
 	input := RecordRequest{
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-		Amount:          20.47,
+		Amount:          money.MustParse("20.47", money.USD),
 	}
 
 	want := Transactions{
@@ -64,13 +137,42 @@ func TestService_Create(t *testing.T) {
 		Amount:          input.Amount,
 	}
 
-	svc := NewService(mockRepo, nil, mockIDGen)
+	svc := NewService(mockRepo, nil, mockIDGen, RateSelection{})
 	got, gotErr := svc.Create(context.Background(), input)
 	assert.NoError(t, gotErr)
 	assert.Equal(t, id, got)
 	assert.Equal(t, want, mockRepo.receivedCreateInput)
 }
 
+func TestService_Create_IdempotencyKey(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	mockRepo := &stubRepository{
+		create: func(ctx context.Context, txn Transactions) (string, error) {
+			return id, nil
+		},
+	}
+
+	mockIDGen := func() string {
+		return id
+	}
+
+	input := RecordRequest{
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.47", money.USD),
+		Idempotency:     &idempotency.Reservation{Key: "key-1", RequestHash: "hash"},
+	}
+
+	svc := NewService(mockRepo, nil, mockIDGen, RateSelection{})
+	got, gotErr := svc.Create(context.Background(), input)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, id, got)
+	assert.Equal(t, *input.Idempotency, mockRepo.receivedResponse.Reservation)
+	assert.Equal(t, http.StatusCreated, mockRepo.receivedResponse.StatusCode)
+	assert.JSONEq(t, `{"id":"`+id+`"}`, string(mockRepo.receivedResponse.Body))
+}
+
 func TestService_Create_Error(t *testing.T) {
 	someErr := errors.New("some error")
 
@@ -83,7 +185,7 @@ func TestService_Create_Error(t *testing.T) {
 			input: RecordRequest{
 				Description:     "food",
 				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-				Amount:          -5,
+				Amount:          money.MustParse("-5", money.USD),
 			},
 			mockRepo: &stubRepository{
 				create: func(ctx context.Context, txn Transactions) (string, error) {
@@ -96,7 +198,7 @@ func TestService_Create_Error(t *testing.T) {
 			input: RecordRequest{
 				Description:     "food",
 				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-				Amount:          20.47,
+				Amount:          money.MustParse("20.47", money.USD),
 			},
 			mockRepo: &stubRepository{
 				create: func(ctx context.Context, txn Transactions) (string, error) {
@@ -113,7 +215,7 @@ func TestService_Create_Error(t *testing.T) {
 				return "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 			}
 
-			svc := NewService(tc.mockRepo, nil, mockIDGen)
+			svc := NewService(tc.mockRepo, nil, mockIDGen, RateSelection{})
 			got, gotErr := svc.Create(context.Background(), tc.input)
 			assert.Empty(t, got)
 			assert.ErrorContains(t, gotErr, tc.wantErr.Error())
@@ -121,13 +223,118 @@ func TestService_Create_Error(t *testing.T) {
 	}
 }
 
+func TestService_Import(t *testing.T) {
+	ids := []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42", "f3a1c2d4-0008-4148-99f6-9c8086a1dd42"}
+	date := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
+
+	mockRepo := &stubRepository{
+		createMany: func(ctx context.Context, txns []Transactions) error {
+			return nil
+		},
+	}
+
+	next := 0
+	mockIDGen := func() string {
+		next++
+		return ids[next-1]
+	}
+
+	body := "description,transaction_date,amount\n" +
+		"food,2023-09-21T00:00:00Z,20.20\n" +
+		",2023-09-21,5\n" +
+		"rent,2023-09-21T03:00:00+03:00,1000\n"
+
+	svc := NewService(mockRepo, nil, mockIDGen, RateSelection{})
+	got, gotErr := svc.Import(context.Background(), ImportRequest{Format: ImportCSV, Body: strings.NewReader(body)})
+	assert.NoError(t, gotErr)
+
+	want := &ImportResponse{
+		Accepted: 2,
+		Rejected: 1,
+		Rows: []ImportRow{
+			{Line: 2, ID: ids[0]},
+			{Line: 3, Error: "description is required"},
+			{Line: 4, ID: ids[1]},
+		},
+	}
+
+	wantTxns := []Transactions{
+		{ID: ids[0], Description: "food", TransactionDate: date, Amount: money.MustParse("20.20", money.USD)},
+		{ID: ids[1], Description: "rent", TransactionDate: date, Amount: money.MustParse("1000", money.USD)},
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, wantTxns, mockRepo.receivedCreateMany)
+}
+
+func TestService_Import_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    ImportRequest
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"validation error": {
+			input:    ImportRequest{Format: "xml", Body: strings.NewReader("<transactions/>")},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: ImportRequest{Format: ImportJSONL, Body: strings.NewReader(`{"description":"food","transaction_date":"2023-09-21T00:00:00Z","amount":20.2}`)},
+			mockRepo: &stubRepository{
+				createMany: func(ctx context.Context, txns []Transactions) error {
+					return someErr
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockIDGen := func() string {
+				return "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+			}
+
+			svc := NewService(tc.mockRepo, nil, mockIDGen, RateSelection{})
+			got, gotErr := svc.Import(context.Background(), tc.input)
+			assert.Nil(t, got)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestService_Import_TooLarge(t *testing.T) {
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("description,transaction_date,amount\nfood,2023-09-21,20.20\n")), 16)
+
+	svc := NewService(&stubRepository{}, nil, nil, RateSelection{})
+	got, gotErr := svc.Import(context.Background(), ImportRequest{Format: ImportCSV, Body: body})
+
+	var tooLarge *http.MaxBytesError
+	assert.Nil(t, got)
+	assert.ErrorAs(t, gotErr, &tooLarge)
+	assert.NotErrorIs(t, gotErr, httpresponse.ErrValidation)
+}
+
+func TestService_Import_AllRejected(t *testing.T) {
+	svc := NewService(&stubRepository{}, nil, nil, RateSelection{})
+	got, gotErr := svc.Import(context.Background(), ImportRequest{Format: ImportJSONL, Body: strings.NewReader(`{"description":"food"}`)})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, &ImportResponse{
+		Rejected: 1,
+		Rows:     []ImportRow{{Line: 1, Error: "transaction date is required"}},
+	}, got)
+}
+
 func TestService_Get(t *testing.T) {
 	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 	retrieve := &Transactions{
 		ID:              id,
 		Description:     "food",
 		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-		Amount:          23.12,
+		Amount:          money.MustParse("23.12", money.USD),
+		Version:         2,
 	}
 
 	mockRepo := &stubRepository{
@@ -140,43 +347,192 @@ func TestService_Get(t *testing.T) {
 		return id
 	}
 
-	mockGw := &stubGateway{
-		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
-			return &gateway.CurrencyExchangeRate{
-				CountryCurrencyDesc: "Brazil-Real",
-				ExchangeRate:        "3.456",
-			}, nil
+	ageDays := 6
+	want := &RetrieveResponse{
+		ID:                  retrieve.ID,
+		Description:         retrieve.Description,
+		TransactionDate:     retrieve.TransactionDate,
+		OriginalAmount:      retrieve.Amount,
+		ExchangeRate:        money.MustParseRate("3.456"),
+		ConvertedAmount:     money.MustParse("79.90", "BRL"),
+		CountryCurrencyDesc: "Brazil-Real",
+		RateProvider:        "treasury",
+		RateDate:            "2023-09-15",
+		RateAgeDays:         &ageDays,
+		Version:             2,
+		ETag:                httpresponse.FormatETag(2, "2023-09-15", "3.456", "Brazil-Real", "treasury"),
+	}
+
+	wantGwInput := gateway.CurrencyExchangeRateRequest{
+		TransactionDate: retrieve.TransactionDate,
+		Country:         "Brazil",
+		Currency:        "Real",
+		LookbackMonths:  gateway.DefaultLookbackMonths,
+		Policy:          gateway.PolicyOnOrBefore,
+	}
+
+	testCases := map[string]struct {
+		country  string
+		currency string
+	}{
+		"treasury names":                     {country: "Brazil", currency: "Real"},
+		"iso currency code":                  {currency: "BRL"},
+		"iso currency and country codes":     {country: "br", currency: "brl"},
+		"iso country code and treasury name": {country: "BR", currency: "Real"},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockGw := &stubProvider{
+				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+					return &gateway.CurrencyExchangeRate{
+						CountryCurrencyDesc: "Brazil-Real",
+						ExchangeRate:        "3.456",
+						RecordDate:          "2023-09-15",
+						Provider:            "treasury",
+					}, nil
+				},
+			}
+
+			input := RetrieveRequest{
+				ID:       id,
+				Country:  tc.country,
+				Currency: tc.currency,
+			}
+
+			svc := NewService(mockRepo, mockGw, mockIDGen, RateSelection{})
+			got, gotErr := svc.Get(context.Background(), input)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, want, got)
+			assert.Equal(t, id, mockRepo.receivedFindInput)
+			assert.Equal(t, wantGwInput, mockGw.receivedInput)
+		})
+	}
+}
+
+func TestService_Get_RateSelection(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+
+	testCases := map[string]struct {
+		defaults     RateSelection
+		input        RetrieveRequest
+		wantPolicy   gateway.RatePolicy
+		wantLookback int
+	}{
+		"service defaults": {
+			defaults:     RateSelection{Policy: gateway.PolicyQuarterStart, LookbackMonths: 3},
+			input:        RetrieveRequest{ID: id, Currency: "CAD"},
+			wantPolicy:   gateway.PolicyQuarterStart,
+			wantLookback: 3,
+		},
+		"request policy": {
+			input:        RetrieveRequest{ID: id, Currency: "CAD", RatePolicy: "nearest"},
+			wantPolicy:   gateway.PolicyNearest,
+			wantLookback: gateway.DefaultLookbackMonths,
+		},
+		"request lookback": {
+			defaults:     RateSelection{Policy: gateway.PolicyNearest},
+			input:        RetrieveRequest{ID: id, Currency: "CAD", LookbackMonths: "12"},
+			wantPolicy:   gateway.PolicyNearest,
+			wantLookback: 12,
 		},
 	}
 
-	input := RetrieveRequest{
-		ID:       id,
-		Country:  "Brazil",
-		Currency: "Real",
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockRepo := &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					return &Transactions{
+						ID:              id,
+						TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+						Amount:          money.MustParse("10", money.USD),
+					}, nil
+				},
+			}
+			mockGw := &stubProvider{
+				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+					return &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.35", RecordDate: "2023-06-30"}, nil
+				},
+			}
+
+			svc := NewService(mockRepo, mockGw, nil, tc.defaults)
+			_, gotErr := svc.Get(context.Background(), tc.input)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantPolicy, mockGw.receivedInput.Policy)
+			assert.Equal(t, tc.wantLookback, mockGw.receivedInput.LookbackMonths)
+		})
 	}
+}
 
-	svc := NewService(mockRepo, mockGw, mockIDGen)
-	got, gotErr := svc.Get(context.Background(), input)
-	assert.NoError(t, gotErr)
+func TestService_Get_ETag(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 
-	want := &RetrieveResponse{
-		ID:              retrieve.ID,
-		Description:     retrieve.Description,
-		TransactionDate: retrieve.TransactionDate,
-		OriginalAmount:  retrieve.Amount,
-		ExchangeRate:    3.456,
-		ConvertedAmount: 79.90,
+	mockRepo := &stubRepository{
+		findByID: func(ctx context.Context, id string) (*Transactions, error) {
+			return &Transactions{
+				ID:              id,
+				Description:     "food",
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Amount:          money.MustParse("23.12", money.USD),
+				Version:         2,
+			}, nil
+		},
 	}
 
-	wantGwInput := gateway.CurrencyExchangeRateRequest{
-		TransactionDate: retrieve.TransactionDate,
-		Country:         input.Country,
-		Currency:        input.Currency,
+	rate := gateway.CurrencyExchangeRate{
+		CountryCurrencyDesc: "Brazil-Real",
+		ExchangeRate:        "3.456",
+		RecordDate:          "2023-09-15",
+		Provider:            "treasury",
 	}
+	etag := httpresponse.FormatETag(2, rate.RecordDate, rate.ExchangeRate, rate.CountryCurrencyDesc, rate.Provider)
 
-	assert.Equal(t, want, got)
-	assert.Equal(t, id, mockRepo.receivedFindInput)
-	assert.Equal(t, wantGwInput, mockGw.receivedGwInput)
+	testCases := map[string]struct {
+		ifNoneMatch     string
+		rate            gateway.CurrencyExchangeRate
+		wantNotModified bool
+	}{
+		"no If-None-Match": {rate: rate},
+		"same version and exchange rate": {
+			ifNoneMatch:     etag,
+			rate:            rate,
+			wantNotModified: true,
+		},
+		"older version": {
+			ifNoneMatch: httpresponse.FormatETag(1, rate.RecordDate, rate.ExchangeRate, rate.CountryCurrencyDesc, rate.Provider),
+			rate:        rate,
+		},
+		"exchange rate synced since": {
+			ifNoneMatch: etag,
+			rate:        gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "3.5", RecordDate: "2023-09-15", Provider: "treasury"},
+		},
+		"exchange rate from another provider": {
+			ifNoneMatch: etag,
+			rate:        gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Brazil-Real", ExchangeRate: "3.456", Provider: "static"},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockGw := &stubProvider{
+				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+					rate := tc.rate
+					return &rate, nil
+				},
+			}
+
+			svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+			got, gotErr := svc.Get(context.Background(), RetrieveRequest{ID: id, Currency: "BRL", IfNoneMatch: tc.ifNoneMatch})
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantNotModified, got.NotModified)
+			if tc.wantNotModified {
+				assert.Equal(t, &RetrieveResponse{ID: id, Version: 2, ETag: etag, NotModified: true}, got)
+			} else {
+				assert.NotEqual(t, tc.ifNoneMatch, got.ETag)
+				assert.Equal(t, money.MustParse("23.12", money.USD), got.OriginalAmount)
+			}
+		})
+	}
 }
 
 func TestService_Get_Error(t *testing.T) {
@@ -185,7 +541,7 @@ func TestService_Get_Error(t *testing.T) {
 	testCases := map[string]struct {
 		input    RetrieveRequest
 		mockRepo *stubRepository
-		mockGw   *stubGateway
+		mockGw   *stubProvider
 		wantErr  error
 	}{
 		"validation error": {
@@ -199,9 +555,57 @@ func TestService_Get_Error(t *testing.T) {
 					return nil, nil
 				},
 			},
-			mockGw:  &stubGateway{},
+			mockGw:  &stubProvider{},
 			wantErr: httpresponse.ErrValidation,
 		},
+		"currency country required": {
+			input: RetrieveRequest{
+				ID:       "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency: "Real",
+			},
+			mockRepo: &stubRepository{},
+			mockGw:   &stubProvider{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"ambiguous currency code": {
+			input: RetrieveRequest{
+				ID:       "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency: "XOF",
+			},
+			mockRepo: &stubRepository{},
+			mockGw:   &stubProvider{},
+			wantErr:  currency.ErrAmbiguousCurrency,
+		},
+		"invalid rate policy": {
+			input: RetrieveRequest{
+				ID:         "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency:   "BRL",
+				RatePolicy: "latest",
+			},
+			mockRepo: &stubRepository{},
+			mockGw:   &stubProvider{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"lookback months not an integer": {
+			input: RetrieveRequest{
+				ID:             "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency:       "BRL",
+				LookbackMonths: "six",
+			},
+			mockRepo: &stubRepository{},
+			mockGw:   &stubProvider{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"lookback months out of range": {
+			input: RetrieveRequest{
+				ID:             "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency:       "BRL",
+				LookbackMonths: "121",
+			},
+			mockRepo: &stubRepository{},
+			mockGw:   &stubProvider{},
+			wantErr:  httpresponse.ErrValidation,
+		},
 		"repository error": {
 			input: RetrieveRequest{
 				ID:       "b62a64c9-0008-4148-99f6-9c8086a1dd42",
@@ -213,7 +617,7 @@ func TestService_Get_Error(t *testing.T) {
 					return nil, someErr
 				},
 			},
-			mockGw:  &stubGateway{},
+			mockGw:  &stubProvider{},
 			wantErr: someErr,
 		},
 		"gateway error": {
@@ -228,11 +632,11 @@ func TestService_Get_Error(t *testing.T) {
 						ID:              id,
 						Description:     "food",
 						TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-						Amount:          23.12,
+						Amount:          money.MustParse("23.12", money.USD),
 					}, nil
 				},
 			},
-			mockGw: &stubGateway{
+			mockGw: &stubProvider{
 				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
 					return nil, someErr
 				},
@@ -247,10 +651,705 @@ func TestService_Get_Error(t *testing.T) {
 				return "b62a64c9-0008-4148-99f6-9c8086a1dd42"
 			}
 
-			svc := NewService(tc.mockRepo, tc.mockGw, mockIDGen)
+			svc := NewService(tc.mockRepo, tc.mockGw, mockIDGen, RateSelection{})
 			got, gotErr := svc.Get(context.Background(), tc.input)
 			assert.Nil(t, got)
 			assert.ErrorContains(t, gotErr, tc.wantErr.Error())
 		})
 	}
 }
+
+func TestService_Get_Span(t *testing.T) {
+	testCases := map[string]struct {
+		findErr    error
+		wantStatus codes.Code
+	}{
+		"not found": {
+			findErr:    fmt.Errorf("%w transaction ID 1", httpresponse.ErrNotFound),
+			wantStatus: codes.Unset,
+		},
+		"database error": {
+			findErr:    errors.New("database is locked"),
+			wantStatus: codes.Error,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			prev := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+			var repoSpan trace.SpanContext
+			mockRepo := &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					repoSpan = trace.SpanContextFromContext(ctx)
+					return nil, tc.findErr
+				},
+			}
+
+			svc := NewService(mockRepo, &stubProvider{}, nil, RateSelection{})
+			_, gotErr := svc.Get(context.Background(), RetrieveRequest{
+				ID:       "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+				Currency: "BRL",
+			})
+			assert.ErrorIs(t, gotErr, tc.findErr)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "Service.Get", spans[0].Name())
+			assert.Equal(t, spans[0].SpanContext().SpanID(), repoSpan.SpanID())
+			assert.Equal(t, tc.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
+
+func TestService_List(t *testing.T) {
+	txns := []Transactions{
+		{
+			ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("23.12", money.USD),
+		},
+		{
+			ID:              "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+			Description:     "taxi",
+			TransactionDate: time.Date(2023, time.September, 20, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("12.00", money.USD),
+		},
+		{
+			ID:              "0c6f1e2a-0008-4148-99f6-9c8086a1dd42",
+			Description:     "hotel",
+			TransactionDate: time.Date(2023, time.September, 19, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("120.00", money.USD),
+		},
+	}
+
+	testCases := map[string]struct {
+		input          ListRequest
+		repoResult     []Transactions
+		wantIDs        []string
+		wantNextCursor bool
+	}{
+		"last page": {
+			input:      ListRequest{Limit: "3"},
+			repoResult: txns,
+			wantIDs:    []string{txns[0].ID, txns[1].ID, txns[2].ID},
+		},
+		"more pages": {
+			input:          ListRequest{Limit: "2"},
+			repoResult:     txns,
+			wantIDs:        []string{txns[0].ID, txns[1].ID},
+			wantNextCursor: true,
+		},
+		"no transactions": {
+			input:      ListRequest{},
+			repoResult: []Transactions{},
+			wantIDs:    []string{},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockRepo := &stubRepository{
+				list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+					if len(tc.repoResult) > filter.Limit {
+						return tc.repoResult[:filter.Limit], nil
+					}
+					return tc.repoResult, nil
+				},
+			}
+
+			svc := NewService(mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.List(context.Background(), tc.input)
+			assert.NoError(t, gotErr)
+
+			gotIDs := make([]string, 0, len(got.Data))
+			for _, txn := range got.Data {
+				gotIDs = append(gotIDs, txn.ID)
+			}
+
+			assert.Equal(t, tc.wantIDs, gotIDs)
+			assert.Equal(t, tc.wantNextCursor, got.NextCursor != "")
+
+			if tc.wantNextCursor {
+				cursor, err := decodeCursor(got.NextCursor)
+				assert.NoError(t, err)
+				assert.Equal(t, txns[1].ID, cursor.ID)
+				assert.Equal(t, DefaultSort.String(), cursor.Sort)
+			}
+		})
+	}
+}
+
+func TestService_List_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    ListRequest
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"validation error": {
+			input:    ListRequest{Limit: "0"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: ListRequest{},
+			mockRepo: &stubRepository{
+				list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+					return nil, someErr
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(tc.mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.List(context.Background(), tc.input)
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr.Error())
+		})
+	}
+}
+
+func TestService_ConvertBatch(t *testing.T) {
+	txns := []Transactions{
+		{
+			ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			Description:     "food",
+			TransactionDate: time.Date(2023, time.October, 20, 15, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("10.00", money.USD),
+		},
+		{
+			ID:              "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+			Description:     "taxi",
+			TransactionDate: time.Date(2023, time.October, 2, 9, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.00", money.USD),
+		},
+		{
+			ID:              "0c6f1e2a-0008-4148-99f6-9c8086a1dd42",
+			Description:     "hotel",
+			TransactionDate: time.Date(2023, time.September, 29, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("100.00", money.USD),
+		},
+		{
+			ID:              "9d3b7a10-0008-4148-99f6-9c8086a1dd42",
+			Description:     "souvenir",
+			TransactionDate: time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("5.00", money.USD),
+		},
+	}
+	missingID := "5e8f2b44-0008-4148-99f6-9c8086a1dd42"
+
+	mockRepo := &stubRepository{
+		findByIDs: func(ctx context.Context, ids []string) ([]Transactions, error) {
+			return txns, nil
+		},
+	}
+
+	// Rates are published at the end of each quarter: 2023-09-30 and 2023-06-30, none in 2022.
+	mockGw := &stubProvider{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			switch {
+			case !input.TransactionDate.Before(time.Date(2023, time.September, 30, 0, 0, 0, 0, time.UTC)):
+				return &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "2023-09-30"}, nil
+			case !input.TransactionDate.Before(time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC)):
+				return &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.3", RecordDate: "2023-06-30"}, nil
+			default:
+				return nil, httpresponse.ErrNoCurrencyConversion
+			}
+		},
+	}
+
+	input := ConversionRequest{
+		IDs:      []string{txns[0].ID, "invalid-uuid", txns[1].ID, missingID, txns[2].ID, txns[3].ID},
+		Country:  "Canada",
+		Currency: "Dollar",
+	}
+
+	svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+	got, gotErr := svc.ConvertBatch(context.Background(), input)
+	assert.NoError(t, gotErr)
+
+	gotResults := make(map[string]string, len(got.Data))
+	for _, result := range got.Data {
+		if result.Error != "" {
+			gotResults[result.ID] = result.Error
+			continue
+		}
+		gotResults[result.ID] = result.Result.ConvertedAmount.String()
+	}
+
+	wantResults := map[string]string{
+		txns[0].ID:     "13.53",
+		"invalid-uuid": "invalid UUID",
+		txns[1].ID:     "27.06",
+		missingID:      "not found transaction ID " + missingID,
+		txns[2].ID:     "130.00",
+		txns[3].ID:     "error calling exchange rate provider: " + httpresponse.ErrNoCurrencyConversion.Error(),
+	}
+
+	wantGwDates := []time.Time{
+		time.Date(2023, time.October, 20, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, time.September, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	gotGwDates := make([]time.Time, 0, len(mockGw.receivedInputs))
+	for _, gwInput := range mockGw.receivedInputs {
+		gotGwDates = append(gotGwDates, gwInput.TransactionDate)
+	}
+
+	assert.Len(t, got.Data, len(input.IDs))
+	assert.Equal(t, input.IDs[1], got.Data[1].ID)
+	assert.Equal(t, wantResults, gotResults)
+	assert.Equal(t, wantGwDates, gotGwDates)
+	assert.Equal(t, []string{txns[0].ID, txns[1].ID, missingID, txns[2].ID, txns[3].ID}, mockRepo.receivedFindIDs)
+}
+
+func TestService_ConvertBatch_RatePolicy(t *testing.T) {
+	txns := []Transactions{
+		{
+			ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			TransactionDate: time.Date(2023, time.October, 20, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("10.00", money.USD),
+		},
+		{
+			ID:              "f3a1c2d4-0008-4148-99f6-9c8086a1dd42",
+			TransactionDate: time.Date(2023, time.October, 2, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("20.00", money.USD),
+		},
+	}
+
+	mockRepo := &stubRepository{
+		findByIDs: func(ctx context.Context, ids []string) ([]Transactions, error) {
+			return txns, nil
+		},
+	}
+
+	mockGw := &stubProvider{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			return &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "2023-09-30"}, nil
+		},
+	}
+
+	input := ConversionRequest{
+		IDs:            []string{txns[0].ID, txns[1].ID},
+		Currency:       "CAD",
+		RatePolicy:     "nearest",
+		LookbackMonths: 2,
+	}
+
+	svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+	got, gotErr := svc.ConvertBatch(context.Background(), input)
+	assert.NoError(t, gotErr)
+	assert.Len(t, got.Data, 2)
+
+	// The nearest rate of a day is not the nearest one of the earlier days, so every day is resolved.
+	assert.Len(t, mockGw.receivedInputs, 2)
+	for _, gwInput := range mockGw.receivedInputs {
+		assert.Equal(t, gateway.PolicyNearest, gwInput.Policy)
+		assert.Equal(t, 2, gwInput.LookbackMonths)
+	}
+}
+
+func TestService_ConvertBatch_Filter(t *testing.T) {
+	txn := Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.October, 20, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("10.00", money.USD),
+	}
+
+	mockRepo := &stubRepository{
+		list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+			return []Transactions{txn}, nil
+		},
+	}
+
+	mockGw := &stubProvider{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			return &gateway.CurrencyExchangeRate{CountryCurrencyDesc: "Canada-Dollar", ExchangeRate: "1.353", RecordDate: "2023-09-30"}, nil
+		},
+	}
+
+	input := ConversionRequest{
+		Filter:   &ConversionFilter{From: "2023-10-01", To: "2023-10-31"},
+		Country:  "Canada",
+		Currency: "Dollar",
+	}
+
+	svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+	got, gotErr := svc.ConvertBatch(context.Background(), input)
+	assert.NoError(t, gotErr)
+
+	assert.Len(t, got.Data, 1)
+	assert.Equal(t, money.MustParse("13.53", "CAD"), got.Data[0].Result.ConvertedAmount)
+	assert.Equal(t, time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC), mockRepo.receivedListInput.DateFrom)
+	assert.Equal(t, MaxConversionItems+1, mockRepo.receivedListInput.Limit)
+}
+
+func TestService_ConvertBatch_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    ConversionRequest
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"validation error": {
+			input:    ConversionRequest{Country: "Canada", Currency: "Dollar"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid lookback months": {
+			input: ConversionRequest{
+				IDs:            []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+				Currency:       "CAD",
+				LookbackMonths: -1,
+			},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid filter": {
+			input: ConversionRequest{
+				Filter:   &ConversionFilter{From: "yesterday"},
+				Country:  "Canada",
+				Currency: "Dollar",
+			},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"filter matches too many transactions": {
+			input: ConversionRequest{
+				Filter:   &ConversionFilter{},
+				Country:  "Canada",
+				Currency: "Dollar",
+			},
+			mockRepo: &stubRepository{
+				list: func(ctx context.Context, filter ListFilter) ([]Transactions, error) {
+					return make([]Transactions, MaxConversionItems+1), nil
+				},
+			},
+			wantErr: httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: ConversionRequest{
+				IDs:      []string{"b62a64c9-0008-4148-99f6-9c8086a1dd42"},
+				Country:  "Canada",
+				Currency: "Dollar",
+			},
+			mockRepo: &stubRepository{
+				findByIDs: func(ctx context.Context, ids []string) ([]Transactions, error) {
+					return nil, someErr
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(tc.mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.ConvertBatch(context.Background(), tc.input)
+			assert.Nil(t, got)
+			assert.ErrorContains(t, gotErr, tc.wantErr.Error())
+		})
+	}
+}
+
+func TestConvert_RateDate(t *testing.T) {
+	txn := Transactions{
+		ID:              "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.October, 2, 23, 30, 0, 0, time.UTC),
+		Amount:          money.MustParse("10", money.USD),
+	}
+
+	testCases := map[string]struct {
+		recordDate  string
+		wantDate    string
+		wantAgeDays *int
+	}{
+		"same day": {
+			recordDate:  "2023-10-02",
+			wantDate:    "2023-10-02",
+			wantAgeDays: ptr(0),
+		},
+		"end of previous quarter": {
+			recordDate:  "2023-09-30",
+			wantDate:    "2023-09-30",
+			wantAgeDays: ptr(2),
+		},
+		"rate without date": {},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			rate := &gateway.CurrencyExchangeRate{
+				CountryCurrencyDesc: "Canada-Dollar",
+				ExchangeRate:        "1.35",
+				RecordDate:          tc.recordDate,
+				Provider:            "static",
+			}
+
+			got, gotErr := convert(txn, rate, "CAD")
+			assert.NoError(t, gotErr)
+			assert.Equal(t, "Canada-Dollar", got.CountryCurrencyDesc)
+			assert.Equal(t, "static", got.RateProvider)
+			assert.Equal(t, tc.wantDate, got.RateDate)
+			assert.Equal(t, tc.wantAgeDays, got.RateAgeDays)
+		})
+	}
+}
+
+func TestConvert_InvalidRateDate(t *testing.T) {
+	txn := Transactions{
+		TransactionDate: time.Date(2023, time.October, 2, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("10", money.USD),
+	}
+	rate := &gateway.CurrencyExchangeRate{ExchangeRate: "1.35", RecordDate: "30/09/2023"}
+
+	got, gotErr := convert(txn, rate, "CAD")
+	assert.Nil(t, got)
+	assert.ErrorContains(t, gotErr, "error parsing exchange rate date")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestService_Update(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	description := "groceries"
+
+	mockRepo := &stubRepository{
+		findByID: func(ctx context.Context, id string) (*Transactions, error) {
+			return &Transactions{
+				ID:              id,
+				Description:     "food",
+				TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+				Amount:          money.MustParse("20.20", money.USD),
+				Version:         2,
+			}, nil
+		},
+		update: func(ctx context.Context, txn Transactions, actor string) error {
+			return nil
+		},
+	}
+
+	svc := NewService(mockRepo, nil, nil, RateSelection{})
+	got, gotErr := svc.Update(context.Background(), UpdateRequest{ID: id, Actor: "jane", Version: 2, Description: &description})
+	assert.NoError(t, gotErr)
+
+	want := Transactions{
+		ID:              id,
+		Description:     description,
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.20", money.USD),
+		Version:         2,
+	}
+
+	assert.Equal(t, &TransactionResponse{
+		ID:              want.ID,
+		Description:     want.Description,
+		TransactionDate: want.TransactionDate,
+		Amount:          want.Amount,
+		Version:         3,
+	}, got)
+	assert.Equal(t, id, mockRepo.receivedFindInput)
+	assert.Equal(t, want, mockRepo.receivedUpdateInput)
+	assert.Equal(t, "jane", mockRepo.receivedActor)
+}
+
+func TestService_Update_Error(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	description := "groceries"
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    UpdateRequest
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"validation error": {
+			input:    UpdateRequest{ID: id, Actor: "jane"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"not found": {
+			input: UpdateRequest{ID: id, Actor: "jane", Description: &description},
+			mockRepo: &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					return nil, httpresponse.ErrNotFound
+				},
+			},
+			wantErr: httpresponse.ErrNotFound,
+		},
+		"version mismatch": {
+			input: UpdateRequest{ID: id, Actor: "jane", Version: 1, Description: &description},
+			mockRepo: &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					return &Transactions{ID: id, Version: 2}, nil
+				},
+			},
+			wantErr: httpresponse.ErrPreconditionFailed,
+		},
+		"changed concurrently": {
+			input: UpdateRequest{ID: id, Actor: "jane", Description: &description},
+			mockRepo: &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					return &Transactions{ID: id, Version: 2}, nil
+				},
+				update: func(ctx context.Context, txn Transactions, actor string) error {
+					return httpresponse.ErrPreconditionFailed
+				},
+			},
+			wantErr: httpresponse.ErrPreconditionFailed,
+		},
+		"repository error": {
+			input: UpdateRequest{ID: id, Actor: "jane", Description: &description},
+			mockRepo: &stubRepository{
+				findByID: func(ctx context.Context, id string) (*Transactions, error) {
+					return &Transactions{ID: id}, nil
+				},
+				update: func(ctx context.Context, txn Transactions, actor string) error {
+					return someErr
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(tc.mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.Update(context.Background(), tc.input)
+			assert.Nil(t, got)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input    DeleteRequest
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"deleted": {
+			input: DeleteRequest{ID: id, Actor: "jane", Version: 3},
+			mockRepo: &stubRepository{
+				delete: func(ctx context.Context, id, actor string, version int) error {
+					return nil
+				},
+			},
+		},
+		"validation error": {
+			input:    DeleteRequest{ID: id},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: DeleteRequest{ID: id, Actor: "jane"},
+			mockRepo: &stubRepository{
+				delete: func(ctx context.Context, id, actor string, version int) error {
+					return someErr
+				},
+			},
+			wantErr: someErr,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(tc.mockRepo, nil, nil, RateSelection{})
+			gotErr := svc.Delete(context.Background(), tc.input)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			if tc.wantErr == nil {
+				assert.Equal(t, tc.input.Version, tc.mockRepo.receivedVersion)
+			}
+		})
+	}
+}
+
+func TestService_History(t *testing.T) {
+	id := "b62a64c9-0008-4148-99f6-9c8086a1dd42"
+	revision := Revision{
+		Description:     "food",
+		TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
+		Amount:          money.MustParse("20.20", money.USD),
+		Change:          ChangeUpdate,
+		ChangedBy:       "jane",
+		ChangedAt:       time.Date(2023, time.October, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	testCases := map[string]struct {
+		revisions []Revision
+		want      *HistoryResponse
+	}{
+		"with revisions": {
+			revisions: []Revision{revision},
+			want:      &HistoryResponse{ID: id, Data: []Revision{revision}},
+		},
+		"never changed": {
+			want: &HistoryResponse{ID: id, Data: []Revision{}},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockRepo := &stubRepository{
+				history: func(ctx context.Context, id string) ([]Revision, error) {
+					return tc.revisions, nil
+				},
+			}
+
+			svc := NewService(mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.History(context.Background(), id)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestService_History_Error(t *testing.T) {
+	testCases := map[string]struct {
+		id       string
+		mockRepo *stubRepository
+		wantErr  error
+	}{
+		"invalid UUID": {
+			id:       "123",
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"not found": {
+			id: "b62a64c9-0008-4148-99f6-9c8086a1dd42",
+			mockRepo: &stubRepository{
+				history: func(ctx context.Context, id string) ([]Revision, error) {
+					return nil, httpresponse.ErrNotFound
+				},
+			},
+			wantErr: httpresponse.ErrNotFound,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(tc.mockRepo, nil, nil, RateSelection{})
+			got, gotErr := svc.History(context.Background(), tc.id)
+			assert.Nil(t, got)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
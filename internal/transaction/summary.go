@@ -0,0 +1,239 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vickiliou/challenge-wex/internal/currency"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+// Periods transactions are grouped by in a summary.
+const (
+	GroupByMonth = "month"
+	GroupByWeek  = "week"
+	GroupByDay   = "day"
+)
+
+// DayAmount represents the number of transactions of a calendar day that have the same amount.
+type DayAmount struct {
+	Day    time.Time
+	Amount money.Money
+	Count  int
+}
+
+// SummaryRequest represents a request to summarize the transactions between two dates by period, in USD and
+// converted to a target currency. GroupBy defaults to GroupByMonth; the other fields are as in ExportRequest.
+type SummaryRequest struct {
+	GroupBy        string
+	From           string
+	To             string
+	Country        string
+	Currency       string
+	RatePolicy     string
+	LookbackMonths string
+}
+
+// SummaryResponse represents the summary of every period with transactions, from the oldest.
+type SummaryResponse struct {
+	GroupBy  string          `json:"group_by"`
+	Currency string          `json:"currency"`
+	Data     []SummaryPeriod `json:"data"`
+}
+
+// SummaryPeriod represents the summary of the transactions of a period, from its first to its last day.
+// Converted only covers the transactions with an exchange rate, the others are counted in Unconverted.
+type SummaryPeriod struct {
+	Period      string         `json:"period"`
+	Start       string         `json:"start"`
+	End         string         `json:"end"`
+	Original    AmountSummary  `json:"original"`
+	Converted   *AmountSummary `json:"converted,omitempty"`
+	Unconverted int            `json:"unconverted_count"`
+}
+
+// AmountSummary represents the number, total, smallest, largest and average amount of a set of transactions.
+type AmountSummary struct {
+	Count   int         `json:"count"`
+	Total   money.Money `json:"total"`
+	Min     money.Money `json:"min"`
+	Max     money.Money `json:"max"`
+	Average money.Money `json:"average"`
+}
+
+// add adds count transactions of the given amount to the summary.
+func (a *AmountSummary) add(amount money.Money, count int) {
+	if a.Count == 0 || amount.MinorUnits() < a.Min.MinorUnits() {
+		a.Min = amount
+	}
+	if a.Count == 0 || amount.MinorUnits() > a.Max.MinorUnits() {
+		a.Max = amount
+	}
+
+	a.Count += count
+	a.Total = money.New(a.Total.MinorUnits()+amount.MinorUnits()*int64(count), amount.Currency())
+	a.Average = a.Total.Divide(int64(a.Count))
+}
+
+// validate checks if the summary request data is valid.
+func (r *SummaryRequest) validate() error {
+	switch strings.ToLower(r.GroupBy) {
+	case "", GroupByMonth, GroupByWeek, GroupByDay:
+	default:
+		return fmt.Errorf("group_by must be %s, %s or %s", GroupByMonth, GroupByWeek, GroupByDay)
+	}
+
+	if isEmpty(r.Currency) {
+		return errors.New("currency is required")
+	}
+
+	return nil
+}
+
+// groupBy returns the period transactions are grouped by.
+func (r *SummaryRequest) groupBy() string {
+	if r.GroupBy == "" {
+		return GroupByMonth
+	}
+	return strings.ToLower(r.GroupBy)
+}
+
+// Summary totals the transactions between the dates of the request by period. Each transaction is converted
+// to the target currency with the exchange rate of its own day, as in Get, so the converted totals are the sums
+// of the converted amounts. Transactions without an exchange rate are counted as unconverted, but any other
+// failure to convert, such as an unavailable exchange rate service, fails the summary. The repository only
+// returns the distinct amounts of each day, so that the summary does not depend on the number of transactions.
+func (s *Service) Summary(ctx context.Context, input SummaryRequest) (_ *SummaryResponse, err error) {
+	ctx, span := startSpan(ctx, "Summary")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	target, err := currency.Resolve(input.Country, input.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	lookbackMonths := 0
+	if !isEmpty(input.LookbackMonths) {
+		if lookbackMonths, err = strconv.Atoi(input.LookbackMonths); err != nil {
+			return nil, fmt.Errorf("%w: lookback_months must be an integer", httpresponse.ErrValidation)
+		}
+	}
+
+	selection, err := s.rateSelection.override(input.RatePolicy, lookbackMonths)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	dates := ListRequest{From: input.From, To: input.To}
+	filter, err := dates.filter()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", httpresponse.ErrValidation, err.Error())
+	}
+
+	amounts, err := s.repo.AmountsByDay(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calling database: %w", err)
+	}
+
+	rates := s.exchangeRatesByDay(ctx, distinctAmountDays(amounts), target, selection)
+
+	groupBy := input.groupBy()
+	code := currencyCode(target)
+
+	res := &SummaryResponse{
+		GroupBy:  groupBy,
+		Currency: code,
+		Data:     []SummaryPeriod{},
+	}
+
+	for _, a := range amounts {
+		period := summaryPeriod(groupBy, a.Day)
+		if n := len(res.Data); n == 0 || res.Data[n-1].Start != period.Start {
+			res.Data = append(res.Data, period)
+		}
+		p := &res.Data[len(res.Data)-1]
+
+		p.Original.add(a.Amount, a.Count)
+
+		converted, err := convertAmount(a.Amount, rates[a.Day], code)
+		if errors.Is(err, httpresponse.ErrNoCurrencyConversion) {
+			p.Unconverted += a.Count
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Converted == nil {
+			p.Converted = &AmountSummary{}
+		}
+		p.Converted.add(converted, a.Count)
+	}
+
+	return res, nil
+}
+
+// convertAmount converts an amount with the exchange rate of its day.
+func convertAmount(amount money.Money, rate dayExchangeRate, currency string) (money.Money, error) {
+	if rate.err != nil {
+		return money.Money{}, rate.err
+	}
+
+	r, err := money.ParseRate(rate.rate.ExchangeRate)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("error parsing exchange rate: %w", err)
+	}
+
+	return amount.Convert(r, currency)
+}
+
+// distinctAmountDays returns the distinct days of amounts ordered by day, most recent first.
+func distinctAmountDays(amounts []DayAmount) []time.Time {
+	var days []time.Time
+	for i := len(amounts) - 1; i >= 0; i-- {
+		if n := len(days); n == 0 || !days[n-1].Equal(amounts[i].Day) {
+			days = append(days, amounts[i].Day)
+		}
+	}
+
+	return days
+}
+
+// summaryPeriod returns the empty summary of the period containing the day. Weeks start on Monday and are
+// labelled by their ISO 8601 week number.
+func summaryPeriod(groupBy string, day time.Time) SummaryPeriod {
+	var (
+		start, end time.Time
+		label      string
+	)
+
+	switch groupBy {
+	case GroupByDay:
+		start, end = day, day
+		label = day.Format(dateFormat)
+	case GroupByWeek:
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		end = start.AddDate(0, 0, 6)
+		year, week := day.ISOWeek()
+		label = fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, -1)
+		label = start.Format("2006-01")
+	}
+
+	return SummaryPeriod{
+		Period: label,
+		Start:  start.Format(dateFormat),
+		End:    end.Format(dateFormat),
+	}
+}
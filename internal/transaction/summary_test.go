@@ -0,0 +1,280 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/httpresponse"
+	"github.com/vickiliou/challenge-wex/internal/money"
+)
+
+func TestService_Summary(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.September, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	amounts := []DayAmount{
+		{Day: day(18), Amount: money.MustParse("0.05", money.USD), Count: 3},
+		{Day: day(18), Amount: money.MustParse("10.00", money.USD), Count: 1},
+		{Day: day(24), Amount: money.MustParse("20.00", money.USD), Count: 1},
+		{Day: day(25), Amount: money.MustParse("7.00", money.USD), Count: 2},
+	}
+
+	mockRepo := &stubRepository{
+		amountsByDay: func(ctx context.Context, filter ListFilter) ([]DayAmount, error) {
+			return amounts, nil
+		},
+	}
+
+	mockGw := &stubProvider{
+		getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+			switch {
+			case input.TransactionDate.Equal(day(24)):
+				return nil, httpresponse.ErrNoCurrencyConversion
+			case input.TransactionDate.Equal(day(25)):
+				return &gateway.CurrencyExchangeRate{ExchangeRate: "2", RecordDate: "2023-09-25"}, nil
+			default:
+				return &gateway.CurrencyExchangeRate{ExchangeRate: "1.5", RecordDate: "2023-09-18"}, nil
+			}
+		},
+	}
+
+	testCases := map[string]struct {
+		groupBy string
+		want    []SummaryPeriod
+	}{
+		"by month": {
+			groupBy: "",
+			want: []SummaryPeriod{
+				{
+					Period: "2023-09",
+					Start:  "2023-09-01",
+					End:    "2023-09-30",
+					Original: AmountSummary{
+						Count:   7,
+						Total:   money.MustParse("44.15", money.USD),
+						Min:     money.MustParse("0.05", money.USD),
+						Max:     money.MustParse("20.00", money.USD),
+						Average: money.MustParse("6.31", money.USD),
+					},
+					Converted: &AmountSummary{
+						Count:   6,
+						Total:   money.MustParse("43.24", "CAD"),
+						Min:     money.MustParse("0.08", "CAD"),
+						Max:     money.MustParse("15.00", "CAD"),
+						Average: money.MustParse("7.21", "CAD"),
+					},
+					Unconverted: 1,
+				},
+			},
+		},
+		"by week": {
+			groupBy: "Week",
+			want: []SummaryPeriod{
+				{
+					Period: "2023-W38",
+					Start:  "2023-09-18",
+					End:    "2023-09-24",
+					Original: AmountSummary{
+						Count:   5,
+						Total:   money.MustParse("30.15", money.USD),
+						Min:     money.MustParse("0.05", money.USD),
+						Max:     money.MustParse("20.00", money.USD),
+						Average: money.MustParse("6.03", money.USD),
+					},
+					Converted: &AmountSummary{
+						Count:   4,
+						Total:   money.MustParse("15.24", "CAD"),
+						Min:     money.MustParse("0.08", "CAD"),
+						Max:     money.MustParse("15.00", "CAD"),
+						Average: money.MustParse("3.81", "CAD"),
+					},
+					Unconverted: 1,
+				},
+				{
+					Period: "2023-W39",
+					Start:  "2023-09-25",
+					End:    "2023-10-01",
+					Original: AmountSummary{
+						Count:   2,
+						Total:   money.MustParse("14.00", money.USD),
+						Min:     money.MustParse("7.00", money.USD),
+						Max:     money.MustParse("7.00", money.USD),
+						Average: money.MustParse("7.00", money.USD),
+					},
+					Converted: &AmountSummary{
+						Count:   2,
+						Total:   money.MustParse("28.00", "CAD"),
+						Min:     money.MustParse("14.00", "CAD"),
+						Max:     money.MustParse("14.00", "CAD"),
+						Average: money.MustParse("14.00", "CAD"),
+					},
+				},
+			},
+		},
+		"by day": {
+			groupBy: "day",
+			want: []SummaryPeriod{
+				{
+					Period: "2023-09-18",
+					Start:  "2023-09-18",
+					End:    "2023-09-18",
+					Original: AmountSummary{
+						Count:   4,
+						Total:   money.MustParse("10.15", money.USD),
+						Min:     money.MustParse("0.05", money.USD),
+						Max:     money.MustParse("10.00", money.USD),
+						Average: money.MustParse("2.54", money.USD),
+					},
+					Converted: &AmountSummary{
+						Count:   4,
+						Total:   money.MustParse("15.24", "CAD"),
+						Min:     money.MustParse("0.08", "CAD"),
+						Max:     money.MustParse("15.00", "CAD"),
+						Average: money.MustParse("3.81", "CAD"),
+					},
+				},
+				{
+					Period: "2023-09-24",
+					Start:  "2023-09-24",
+					End:    "2023-09-24",
+					Original: AmountSummary{
+						Count:   1,
+						Total:   money.MustParse("20.00", money.USD),
+						Min:     money.MustParse("20.00", money.USD),
+						Max:     money.MustParse("20.00", money.USD),
+						Average: money.MustParse("20.00", money.USD),
+					},
+					Unconverted: 1,
+				},
+				{
+					Period: "2023-09-25",
+					Start:  "2023-09-25",
+					End:    "2023-09-25",
+					Original: AmountSummary{
+						Count:   2,
+						Total:   money.MustParse("14.00", money.USD),
+						Min:     money.MustParse("7.00", money.USD),
+						Max:     money.MustParse("7.00", money.USD),
+						Average: money.MustParse("7.00", money.USD),
+					},
+					Converted: &AmountSummary{
+						Count:   2,
+						Total:   money.MustParse("28.00", "CAD"),
+						Min:     money.MustParse("14.00", "CAD"),
+						Max:     money.MustParse("14.00", "CAD"),
+						Average: money.MustParse("14.00", "CAD"),
+					},
+				},
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			svc := NewService(mockRepo, mockGw, nil, RateSelection{})
+			got, gotErr := svc.Summary(context.Background(), SummaryRequest{
+				GroupBy:  tc.groupBy,
+				From:     "2023-09-01",
+				To:       "2023-09-30",
+				Currency: "CAD",
+			})
+			assert.NoError(t, gotErr)
+			assert.Equal(t, "CAD", got.Currency)
+			assert.Equal(t, tc.want, got.Data)
+			assert.Equal(t, day(1), mockRepo.receivedListInput.DateFrom)
+			assert.Equal(t, time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC), mockRepo.receivedListInput.DateTo)
+		})
+	}
+}
+
+func TestService_Summary_Empty(t *testing.T) {
+	mockRepo := &stubRepository{
+		amountsByDay: func(ctx context.Context, filter ListFilter) ([]DayAmount, error) {
+			return nil, nil
+		},
+	}
+
+	svc := NewService(mockRepo, &stubProvider{}, nil, RateSelection{})
+	got, gotErr := svc.Summary(context.Background(), SummaryRequest{Currency: "CAD"})
+	assert.NoError(t, gotErr)
+	assert.Equal(t, &SummaryResponse{GroupBy: GroupByMonth, Currency: "CAD", Data: []SummaryPeriod{}}, got)
+}
+
+func TestService_Summary_Error(t *testing.T) {
+	someErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		input        SummaryRequest
+		mockRepo     *stubRepository
+		mockProvider *stubProvider
+		wantErr      error
+	}{
+		"invalid group by": {
+			input:    SummaryRequest{GroupBy: "year", Currency: "CAD"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"missing currency": {
+			input:    SummaryRequest{},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"unknown currency": {
+			input:    SummaryRequest{Currency: "XYZ"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid lookback": {
+			input:    SummaryRequest{Currency: "CAD", LookbackMonths: "six"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"invalid dates": {
+			input:    SummaryRequest{Currency: "CAD", From: "2023-09-30", To: "2023-09-01"},
+			mockRepo: &stubRepository{},
+			wantErr:  httpresponse.ErrValidation,
+		},
+		"repository error": {
+			input: SummaryRequest{Currency: "CAD"},
+			mockRepo: &stubRepository{
+				amountsByDay: func(ctx context.Context, filter ListFilter) ([]DayAmount, error) {
+					return nil, someErr
+				},
+			},
+			wantErr: someErr,
+		},
+		"exchange rate service unavailable": {
+			input: SummaryRequest{Currency: "CAD"},
+			mockRepo: &stubRepository{
+				amountsByDay: func(ctx context.Context, filter ListFilter) ([]DayAmount, error) {
+					return []DayAmount{{Day: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC), Amount: money.MustParse("10.00", money.USD), Count: 1}}, nil
+				},
+			},
+			mockProvider: &stubProvider{
+				getExchangeRate: func(input gateway.CurrencyExchangeRateRequest) (*gateway.CurrencyExchangeRate, error) {
+					return nil, httpresponse.ErrServiceUnavailable
+				},
+			},
+			wantErr: httpresponse.ErrServiceUnavailable,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			mockProvider := tc.mockProvider
+			if mockProvider == nil {
+				mockProvider = &stubProvider{}
+			}
+
+			svc := NewService(tc.mockRepo, mockProvider, nil, RateSelection{})
+			got, gotErr := svc.Summary(context.Background(), tc.input)
+			assert.Nil(t, got)
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+		})
+	}
+}
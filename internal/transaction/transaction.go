@@ -2,10 +2,14 @@ package transaction
 
 import (
 	"errors"
-	"math"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vickiliou/challenge-wex/internal/gateway"
+	"github.com/vickiliou/challenge-wex/internal/idempotency"
+	"github.com/vickiliou/challenge-wex/internal/money"
 )
 
 // Transactions represents a transaction stored in the database.
@@ -13,14 +17,21 @@ type Transactions struct {
 	ID              string
 	Description     string
 	TransactionDate time.Time
-	Amount          float64
+	Amount          money.Money
+
+	// Version is incremented on each change of the transaction.
+	Version int
 }
 
 // RecordRequest represents input data for a transaction request provided by the user.
 type RecordRequest struct {
-	Description     string    `json:"description"`
-	TransactionDate time.Time `json:"transaction_date"`
-	Amount          float64   `json:"amount"`
+	Description     string      `json:"description"`
+	TransactionDate time.Time   `json:"transaction_date"`
+	Amount          money.Money `json:"amount"`
+
+	// Idempotency is the reservation of the idempotency key of the request, if any, completed with the response
+	// together with the creation of the transaction.
+	Idempotency *idempotency.Reservation `json:"-"`
 }
 
 // RecordResponse represents the response for a transaction request.
@@ -29,20 +40,48 @@ type RecordResponse struct {
 }
 
 // RetrieveRequest represents a request to retrieve user transaction data.
+// Currency is an ISO 4217 code or a Treasury currency name, and Country an ISO 3166 code or a Treasury country name.
+// RatePolicy and LookbackMonths override the default rate selection when set.
+// IfNoneMatch is the If-None-Match header of the request, if any.
 type RetrieveRequest struct {
-	ID       string
-	Country  string
-	Currency string
+	ID             string
+	Country        string
+	Currency       string
+	RatePolicy     string
+	LookbackMonths string
+	IfNoneMatch    string
+}
+
+// RateSelection represents how the exchange rate applying to a transaction is selected.
+type RateSelection struct {
+	Policy         gateway.RatePolicy
+	LookbackMonths int
 }
 
 // RetrieveResponse represents user transaction data.
 type RetrieveResponse struct {
-	ID              string    `json:"id"`
-	Description     string    `json:"description"`
-	TransactionDate time.Time `json:"transaction_date"`
-	OriginalAmount  float64   `json:"original_amount"`
-	ExchangeRate    float64   `json:"exchange_rate"`
-	ConvertedAmount float64   `json:"converted_amount"`
+	ID              string      `json:"id"`
+	Description     string      `json:"description"`
+	TransactionDate time.Time   `json:"transaction_date"`
+	OriginalAmount  money.Money `json:"original_amount"`
+	ExchangeRate    money.Rate  `json:"exchange_rate"`
+	ConvertedAmount money.Money `json:"converted_amount"`
+
+	// CountryCurrencyDesc is the Treasury country currency the exchange rate was found for, e.g. "Canada-Dollar".
+	CountryCurrencyDesc string `json:"country_currency_desc,omitempty"`
+	RateProvider        string `json:"exchange_rate_provider,omitempty"`
+
+	// RateDate is the effective date of the exchange rate, and RateAgeDays the number of days between it and
+	// the transaction date. Both are omitted for rates without a date, such as the static overrides.
+	RateDate    string `json:"exchange_rate_date,omitempty"`
+	RateAgeDays *int   `json:"exchange_rate_age_days,omitempty"`
+
+	// Version is the version of the transaction, and ETag identifies the response by that version and
+	// the exchange rate it was converted with. NotModified reports that ETag matches the IfNoneMatch of the
+	// request, in which case the amount is not converted and only ID, Version and ETag are set.
+	Version     int    `json:"-"`
+	ETag        string `json:"-"`
+	NotModified bool   `json:"-"`
 }
 
 // validate checks if the record request data is valid.
@@ -71,9 +110,6 @@ func (r *RetrieveRequest) validate() error {
 	if isValidUUID(r.ID) {
 		return errors.New("invalid UUID")
 	}
-	if isEmpty(r.Country) {
-		return errors.New("currency country is required")
-	}
 
 	if isEmpty(r.Currency) {
 		return errors.New("currency is required")
@@ -81,6 +117,42 @@ func (r *RetrieveRequest) validate() error {
 	return nil
 }
 
+// rateSelection returns the rate selection requested, falling back to the defaults for the unset fields.
+func (r *RetrieveRequest) rateSelection(defaults RateSelection) (RateSelection, error) {
+	var lookbackMonths int
+	if !isEmpty(r.LookbackMonths) {
+		n, err := strconv.Atoi(r.LookbackMonths)
+		if err != nil {
+			return RateSelection{}, errors.New("lookback_months must be an integer")
+		}
+		lookbackMonths = n
+	}
+
+	return defaults.override(r.RatePolicy, lookbackMonths)
+}
+
+// override returns the rate selection with the given policy and lookback, unless they are empty.
+func (d RateSelection) override(policy string, lookbackMonths int) (RateSelection, error) {
+	res := d
+
+	if !isEmpty(policy) {
+		p, err := gateway.ParsePolicy(policy)
+		if err != nil {
+			return RateSelection{}, err
+		}
+		res.Policy = p
+	}
+
+	if lookbackMonths != 0 {
+		if lookbackMonths < 1 || lookbackMonths > gateway.MaxLookbackMonths {
+			return RateSelection{}, fmt.Errorf("lookback_months must be between 1 and %d", gateway.MaxLookbackMonths)
+		}
+		res.LookbackMonths = lookbackMonths
+	}
+
+	return res, nil
+}
+
 // validateDescription checks if the description field is a valid RFC3339 formatted timestamp and not empty.
 func validateDescription(description string) error {
 	if isEmpty(description) {
@@ -108,19 +180,15 @@ func validateTransactionDate(transactionDate time.Time) error {
 }
 
 // validateAmount checks if the amount field is valid and not empty.
-func validateAmount(amount float64) error {
-	if math.IsNaN(amount) {
+func validateAmount(amount money.Money) error {
+	if amount.IsZero() {
 		return errors.New("amount is required")
 	}
 
-	if amount <= 0 {
+	if amount.IsNegative() {
 		return errors.New("amount must be a positive number")
 	}
 
-	if amount != roundTwoDecimal(amount) {
-		return errors.New("amount must be rounded to two decimal places")
-	}
-
 	return nil
 }
 
@@ -134,8 +202,3 @@ func isValidUUID(id string) bool {
 func isEmpty(s string) bool {
 	return len(s) == 0
 }
-
-// roundTwoDecimal rounds a float number to two decimal places.
-func roundTwoDecimal(amount float64) float64 {
-	return math.Round(amount*100) / 100
-}
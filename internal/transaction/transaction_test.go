@@ -1,11 +1,11 @@
 package transaction
 
 import (
-	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vickiliou/challenge-wex/internal/money"
 )
 
 func TestTransaction_RecordRequest_Validate(t *testing.T) {
@@ -13,7 +13,7 @@ func TestTransaction_RecordRequest_Validate(t *testing.T) {
 		input := &RecordRequest{
 			Description:     "food",
 			TransactionDate: time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC),
-			Amount:          40.50,
+			Amount:          money.MustParse("40.50", money.USD),
 		}
 		gotErr := input.validate()
 		assert.Nil(t, gotErr)
@@ -23,7 +23,7 @@ func TestTransaction_RecordRequest_Validate(t *testing.T) {
 func TestTransaction_RecordRequest_Validate_Error(t *testing.T) {
 	description := "food"
 	transactionDate := time.Date(2023, time.September, 21, 0, 0, 0, 0, time.UTC)
-	amount := 40.50
+	amount := money.MustParse("40.50", money.USD)
 
 	testCases := map[string]struct {
 		input   *RecordRequest
@@ -59,7 +59,7 @@ func TestTransaction_RecordRequest_Validate_Error(t *testing.T) {
 			input: &RecordRequest{
 				Description:     description,
 				TransactionDate: time.Date(-999, -99, -99, 0, 0, 0, 0, time.UTC),
-				Amount:          40.50,
+				Amount:          amount,
 			},
 			wantErr: "invalid date format",
 		},
@@ -67,7 +67,7 @@ func TestTransaction_RecordRequest_Validate_Error(t *testing.T) {
 			input: &RecordRequest{
 				Description:     description,
 				TransactionDate: transactionDate,
-				Amount:          math.NaN(),
+				Amount:          money.Money{},
 			},
 			wantErr: "required",
 		},
@@ -75,18 +75,10 @@ func TestTransaction_RecordRequest_Validate_Error(t *testing.T) {
 			input: &RecordRequest{
 				Description:     description,
 				TransactionDate: transactionDate,
-				Amount:          -1,
+				Amount:          money.MustParse("-1", money.USD),
 			},
 			wantErr: "positive number",
 		},
-		"not rounded to two decimal places": {
-			input: &RecordRequest{
-				Description:     description,
-				TransactionDate: transactionDate,
-				Amount:          9.5579,
-			},
-			wantErr: "two decimal places",
-		},
 	}
 
 	for title, tc := range testCases {
@@ -126,14 +118,6 @@ func TestTransaction_RetrieveRequest_Validate_Error(t *testing.T) {
 			},
 			wantError: "invalid UUID",
 		},
-		"empty currency country": {
-			input: &RetrieveRequest{
-				ID:       id,
-				Country:  "",
-				Currency: currency,
-			},
-			wantError: "required",
-		},
 		"empty currency": {
 			input: &RetrieveRequest{
 				ID:       id,